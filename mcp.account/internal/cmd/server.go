@@ -18,16 +18,21 @@ func newServerCommand() *cobra.Command {
 	startCmd := &cobra.Command{
 		Use: "start",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Tool calls cancelled by the client stop the az and azd processes they started.
+			cancellations := newCancellations()
 			s := server.NewMCPServer(
 				"Azure Account",
 				"1.0.0",
 				server.WithToolCapabilities(true),
+				server.WithHooks(cancellations.hooks()),
+				server.WithToolHandlerMiddleware(cancellations.middleware),
 				server.WithRecovery(),
 				server.WithLogging(),
 				server.WithPromptCapabilities(false),
 				server.WithResourceCapabilities(false, false),
 				server.WithInstructions("Supports tools for interacting with Azure accounts, subscriptions and locations."),
 			)
+			s.AddNotificationHandler(methodNotificationCancelled, cancellations.handleCancelled)
 
 			// Subscription tools
 			listSubscriptionsTool := mcp.NewTool(
@@ -35,7 +40,7 @@ func newServerCommand() *cobra.Command {
 				mcp.WithDescription("Lists all Azure subscriptions accessible to the account"),
 			)
 			s.AddTool(listSubscriptionsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-				azCmd := exec.Command("az", "account", "list")
+				azCmd := exec.CommandContext(ctx, "az", "account", "list")
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -45,7 +50,7 @@ func newServerCommand() *cobra.Command {
 				mcp.WithDescription("Lists all Azure locations available for the current account"),
 			)
 			s.AddTool(listLocationsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-				azCmd := exec.Command("az", "account", "list-locations")
+				azCmd := exec.CommandContext(ctx, "az", "account", "list-locations")
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -66,7 +71,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: subscriptionId, expected string"), nil
 				}
-				azCmd := exec.Command("az", "account", "set", "--subscription", subId)
+				azCmd := exec.CommandContext(ctx, "az", "account", "set", "--subscription", subId)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -75,7 +80,7 @@ func newServerCommand() *cobra.Command {
 				mcp.WithDescription("Shows details of the current Azure subscription/account."),
 			)
 			s.AddTool(showAccountTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-				azCmd := exec.Command("az", "account", "show")
+				azCmd := exec.CommandContext(ctx, "az", "account", "show")
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -84,7 +89,7 @@ func newServerCommand() *cobra.Command {
 				mcp.WithDescription("Shows information for the current logged in Azure AD user."),
 			)
 			s.AddTool(showUserTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-				azCmd := exec.Command("az", "ad", "signed-in-user", "show")
+				azCmd := exec.CommandContext(ctx, "az", "ad", "signed-in-user", "show")
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -26,8 +26,12 @@ func newServerCommand() *cobra.Command {
 		Use:   "start",
 		Short: "Get the context of the AZD project & environment.",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Tool calls cancelled by the client stop the az and azd processes they started.
+			cancellations := newCancellations()
 			mcpServer := server.NewMCPServer("azd", "0.0.1",
 				server.WithToolCapabilities(true),
+				server.WithHooks(cancellations.hooks()),
+				server.WithToolHandlerMiddleware(cancellations.middleware),
 				server.WithRecovery(),
 				server.WithLogging(),
 				server.WithPromptCapabilities(false),
@@ -37,6 +41,7 @@ func newServerCommand() *cobra.Command {
 						"If a tool accepts a 'cwd', send the current working directory as the 'cwd' argument.",
 				),
 			)
+			mcpServer.AddNotificationHandler(methodNotificationCancelled, cancellations.handleCancelled)
 
 			registerTools(mcpServer)
 
@@ -369,23 +374,23 @@ func invokeAuthLogin(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 		args = append(args, "--tenant-id", tenantId.(string))
 	}
 
-	return execAzdCommand(request, args)
+	return execAzdCommand(ctx, request, args)
 }
 
 func invokeAuthCheckStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := []string{"auth", "login", "--check-status"}
-	return execAzdCommand(request, args)
+	return execAzdCommand(ctx, request, args)
 }
 
 func invokeTemplateList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := []string{"template", "list", "--output", "json"}
 
-	return execAzdCommand(request, args)
+	return execAzdCommand(ctx, request, args)
 }
 
 func invokeGetEnvValues(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := []string{"env", "get-values"}
-	return execAzdCommand(request, args)
+	return execAzdCommand(ctx, request, args)
 }
 
 func invokeSetEnvValue(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -401,7 +406,7 @@ func invokeSetEnvValue(ctx context.Context, request mcp.CallToolRequest) (*mcp.C
 		args = append(args, value.(string))
 	}
 
-	return execAzdCommand(request, args)
+	return execAzdCommand(ctx, request, args)
 }
 
 func invokeInit(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -422,7 +427,7 @@ func invokeInit(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTool
 		args = append(args, "--template", template.(string))
 	}
 
-	result, err := execAzdCommand(request, args)
+	result, err := execAzdCommand(ctx, request, args)
 	if err == nil {
 		result.Content = append(
 			result.Content,
@@ -437,7 +442,7 @@ func invokeInit(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTool
 
 func invokeEnvList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := []string{"env", "list"}
-	return execAzdCommand(request, args)
+	return execAzdCommand(ctx, request, args)
 }
 
 func invokeNewEnv(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -448,7 +453,7 @@ func invokeNewEnv(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTo
 		args = append(args, name.(string))
 	}
 
-	result, err := execAzdCommand(request, args)
+	result, err := execAzdCommand(ctx, request, args)
 	if err == nil {
 		result.Content = append(result.Content,
 			mcp.NewTextContent(
@@ -465,12 +470,12 @@ func invokeNewEnv(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTo
 
 func invokeShow(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := []string{"show"}
-	return execAzdCommand(request, args)
+	return execAzdCommand(ctx, request, args)
 }
 
 func invokeGlobalConfig(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := []string{"config", "show"}
-	return execAzdCommand(request, args)
+	return execAzdCommand(ctx, request, args)
 }
 
 func invokeProvision(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -486,7 +491,7 @@ func invokeProvision(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 		args = append(args, "--no-state")
 	}
 
-	result, err := execAzdCommand(request, args)
+	result, err := execAzdCommand(ctx, request, args)
 	if err == nil {
 		result.Content = append(
 			result.Content,
@@ -507,7 +512,7 @@ func invokeDeploy(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTo
 		args = append(args, serviceName.(string))
 	}
 
-	result, err := execAzdCommand(request, args)
+	result, err := execAzdCommand(ctx, request, args)
 	if err == nil {
 		result.Content = append(result.Content,
 			mcp.NewTextContent(
@@ -544,12 +549,12 @@ func invokePipelineConfig(ctx context.Context, request mcp.CallToolRequest) (*mc
 		args = append(args, "--remote-name", v.(string))
 	}
 
-	return execAzdCommand(request, args)
+	return execAzdCommand(ctx, request, args)
 }
 
 func invokeUp(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := []string{"up"}
-	return execAzdCommand(request, args)
+	return execAzdCommand(ctx, request, args)
 }
 
 func invokeSelectEnv(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -558,7 +563,7 @@ func invokeSelectEnv(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 	if hasEnv {
 		args = append(args, env.(string))
 	}
-	return execAzdCommand(request, args)
+	return execAzdCommand(ctx, request, args)
 }
 
 func appendGlobalFlags(args []string, request mcp.CallToolRequest) []string {
@@ -581,7 +586,7 @@ func appendGlobalFlags(args []string, request mcp.CallToolRequest) []string {
 	return args
 }
 
-func execAzdCommand(request mcp.CallToolRequest, args []string) (*mcp.CallToolResult, error) {
+func execAzdCommand(ctx context.Context, request mcp.CallToolRequest, args []string) (*mcp.CallToolResult, error) {
 	result := &mcp.CallToolResult{
 		Content: []mcp.Content{},
 	}
@@ -590,7 +595,7 @@ func execAzdCommand(request mcp.CallToolRequest, args []string) (*mcp.CallToolRe
 
 	log.Printf("Running command: azd %s\n",
 		strings.Join(args, " "))
-	resultBytes, err := exec.Command("azd", args...).CombinedOutput()
+	resultBytes, err := exec.CommandContext(ctx, "azd", args...).CombinedOutput()
 	if err != nil {
 		azdOutput := string(resultBytes)
 		log.Printf("Error executing azd command: %s\n", azdOutput)
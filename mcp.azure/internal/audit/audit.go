@@ -0,0 +1,281 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the audit log in the root server's config directory.
+const FileName = "audit.jsonl"
+
+// Defaults for rotating the audit log.
+const (
+	DefaultMaxSize  = 10 * 1024 * 1024
+	DefaultMaxFiles = 5
+)
+
+// rotatedTimeFormat names rotated files so they sort by the time they were rotated.
+const rotatedTimeFormat = "20060102T150405.000000000Z"
+
+// Outcomes of an audited command.
+const (
+	OutcomeSuccess   = "success"
+	OutcomeError     = "error"
+	OutcomeCancelled = "cancelled"
+	// OutcomeDryRun is recorded for commands that were validated but not run.
+	OutcomeDryRun = "dry_run"
+)
+
+// Entry records a single command dispatched to a child tool.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Session string    `json:"session,omitempty"`
+	Intent  string    `json:"intent,omitempty"`
+	Tool    string    `json:"tool"`
+	Command string    `json:"command"`
+	// Parameters are the parameters of the command, with secrets redacted.
+	Parameters any `json:"parameters,omitempty"`
+	// Version is the version of the azd extension that ran the command.
+	Version    string `json:"version,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	Outcome    string `json:"outcome"`
+	ErrorCode  string `json:"errorCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Log is an append-only JSONL audit log. Once the log grows past its maximum size it is
+// renamed with the time of the rotation, and only the newest rotated files are kept.
+type Log struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu sync.Mutex
+}
+
+// Option configures a Log.
+type Option func(*Log)
+
+// WithMaxSize sets the size in bytes after which the log is rotated.
+func WithMaxSize(maxSize int64) Option {
+	return func(l *Log) {
+		l.maxSize = maxSize
+	}
+}
+
+// WithMaxFiles sets how many rotated files are kept next to the log.
+func WithMaxFiles(maxFiles int) Option {
+	return func(l *Log) {
+		l.maxFiles = maxFiles
+	}
+}
+
+// Open opens the audit log at the given path, creating its directory if needed.
+func Open(path string, options ...Option) (*Log, error) {
+	l := &Log{
+		path:     path,
+		maxSize:  DefaultMaxSize,
+		maxFiles: DefaultMaxFiles,
+	}
+	for _, opt := range options {
+		opt(l)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	return l, nil
+}
+
+// Path returns the path of the current audit log file.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Record redacts the entry and appends it to the log. A nil log records nothing.
+func (l *Log) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	entry.Intent = RedactText(entry.Intent)
+	entry.Parameters = Redact(entry.Command, entry.Parameters)
+	entry.Error = RedactText(entry.Error)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.rotate(int64(len(line))); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(line)
+	return err
+}
+
+// rotate renames the log when the next write would take it past its maximum size,
+// and removes the oldest rotated files.
+func (l *Log) rotate(next int64) error {
+	if l.maxSize <= 0 {
+		return nil
+	}
+
+	info, err := os.Stat(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 || info.Size()+next <= l.maxSize {
+		return nil
+	}
+
+	base, ext := splitExt(l.path)
+	rotated := base + "-" + time.Now().UTC().Format(rotatedTimeFormat) + ext
+	if err := os.Rename(l.path, rotated); err != nil {
+		return err
+	}
+
+	files, err := rotatedFiles(l.path)
+	if err != nil {
+		return err
+	}
+	for len(files) > l.maxFiles {
+		if err := os.Remove(files[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		files = files[1:]
+	}
+
+	return nil
+}
+
+// Filter selects audit entries. Zero fields match every entry.
+type Filter struct {
+	Since   time.Time
+	Until   time.Time
+	Tool    string
+	Command string
+	Session string
+	Outcome string
+}
+
+func (f Filter) matches(e Entry) bool {
+	switch {
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && e.Time.After(f.Until):
+		return false
+	case f.Tool != "" && e.Tool != f.Tool:
+		return false
+	case f.Command != "" && e.Command != f.Command:
+		return false
+	case f.Session != "" && e.Session != f.Session:
+		return false
+	case f.Outcome != "" && e.Outcome != f.Outcome:
+		return false
+	default:
+		return true
+	}
+}
+
+// Read returns the entries of the audit log at the given path and of its rotated files
+// that match the filter, oldest first. Lines that cannot be parsed, such as a line cut
+// short by a crash, are skipped.
+func Read(path string, filter Filter) ([]Entry, error) {
+	files, err := rotatedFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, path)
+
+	var entries []Entry
+	for _, file := range files {
+		fileEntries, err := readFile(file, filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	return entries, nil
+}
+
+func readFile(path string, filter Filter) ([]Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+
+	return entries, nil
+}
+
+// rotatedFiles returns the rotated files of the log at the given path, oldest first.
+func rotatedFiles(path string) ([]string, error) {
+	base, ext := splitExt(filepath.Base(path))
+	dirEntries, err := os.ReadDir(filepath.Dir(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if !dirEntry.IsDir() && strings.HasPrefix(name, base+"-") && strings.HasSuffix(name, ext) {
+			files = append(files, filepath.Join(filepath.Dir(path), name))
+		}
+	}
+
+	slices.Sort(files)
+	return files, nil
+}
+
+func splitExt(path string) (string, string) {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext), ext
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces secret values in the audit log.
+const Redacted = "[REDACTED]"
+
+// secretNames are parts of parameter names, lower case and without separators,
+// whose values are treated as secrets. Keys are only matched by the names of actual
+// secret keys, so names such as partitionKey, rowKey or sortKey are kept.
+var secretNames = []string{
+	"password", "passwd", "secret", "token", "connectionstring", "credential", "signature", "sas",
+	"apikey", "accesskey", "accountkey", "sharedaccesskey", "primarykey", "secondarykey",
+	"masterkey", "subscriptionkey", "privatekey",
+}
+
+// secretCommands are parts of command names, lower case, whose commands set a secret, such as
+// set-keyvault-secret or set-environment-value. The values they set are treated as secrets.
+var secretCommands = []string{"secret", "password", "credential", "environment"}
+
+// valueNames are the names, lower case and without separators, of the parameters that carry the
+// value a command sets.
+var valueNames = []string{"value", "secretvalue", "values"}
+
+// secretValues match values that carry secrets whatever the name of their parameter,
+// such as connection strings, SAS tokens, bearer tokens and JWTs.
+var secretValues = regexp.MustCompile(
+	`(?i)(AccountKey|SharedAccessKey|SharedAccessSignature|Password|Pwd)=[^;\s]+` +
+		`|([?&]sig=)[^&\s]+` +
+		`|(Bearer\s+)[A-Za-z0-9\-._~+/]+=*` +
+		`|eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+)
+
+// Redact returns a copy of the parameters of the command with the values of secret parameters,
+// and secrets found in any other value, replaced. The value set by a command that sets a secret
+// is redacted whatever the name of its parameter suggests.
+func Redact(command string, params any) any {
+	return redact(params, isSecretCommand(command))
+}
+
+func redact(params any, secretCommand bool) any {
+	switch v := params.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for name, value := range v {
+			if (isSecretName(name) || (secretCommand && isValueName(name))) && value != nil {
+				redacted[name] = Redacted
+				continue
+			}
+			redacted[name] = redact(value, secretCommand)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, value := range v {
+			redacted[i] = redact(value, secretCommand)
+		}
+		return redacted
+	case string:
+		return RedactText(v)
+	default:
+		return v
+	}
+}
+
+// RedactText replaces the secrets found in free text, such as an intent or an error message.
+func RedactText(text string) string {
+	return secretValues.ReplaceAllStringFunc(text, func(match string) string {
+		if i := strings.IndexAny(match, "=\t "); i >= 0 && !strings.HasPrefix(match, "eyJ") {
+			return match[:i+1] + Redacted
+		}
+		return Redacted
+	})
+}
+
+// isSecretCommand reports whether a command name suggests that the command sets a secret.
+func isSecretCommand(command string) bool {
+	command = strings.ToLower(command)
+	for _, s := range secretCommands {
+		if strings.Contains(command, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isValueName reports whether a parameter name is the value a command sets.
+func isValueName(name string) bool {
+	return slices.Contains(valueNames, normalizeName(name))
+}
+
+// isSecretName reports whether a parameter name suggests that its value is a secret.
+func isSecretName(name string) bool {
+	name = normalizeName(name)
+	if name == "key" {
+		return true
+	}
+	for _, s := range secretNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeName lower cases a parameter name and drops its separators.
+func normalizeName(name string) string {
+	return strings.NewReplacer("-", "", "_", "", ".", "").Replace(strings.ToLower(name))
+}
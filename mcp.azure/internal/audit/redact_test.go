@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		params  map[string]any
+		want    map[string]any
+	}{
+		{
+			name:    "set-keyvault-secret",
+			command: "set-keyvault-secret",
+			params:  map[string]any{"vaultName": "kv-prod", "secretName": "db", "value": "hunter2"},
+			want:    map[string]any{"vaultName": "kv-prod", "secretName": Redacted, "value": Redacted},
+		},
+		{
+			name:    "set-environment-value",
+			command: "set-environment-value",
+			params:  map[string]any{"name": "DB_PASSWORD", "value": "hunter2"},
+			want:    map[string]any{"name": "DB_PASSWORD", "value": Redacted},
+		},
+		{
+			name:    "value of other commands is kept",
+			command: "set-tag",
+			params:  map[string]any{"name": "env", "value": "prod"},
+			want:    map[string]any{"name": "env", "value": "prod"},
+		},
+		{
+			name:    "secret names",
+			command: "create-storage-account",
+			params:  map[string]any{"accountKey": "abc", "api_key": "abc", "key": "abc", "partitionKey": "p1"},
+			want:    map[string]any{"accountKey": Redacted, "api_key": Redacted, "key": Redacted, "partitionKey": "p1"},
+		},
+		{
+			name:    "secret values",
+			command: "run-query",
+			params: map[string]any{"connection": "Endpoint=sb://x/;SharedAccessKey=abc", "nested": []any{
+				map[string]any{"password": "abc"},
+			}},
+			want: map[string]any{"connection": "Endpoint=sb://x/;SharedAccessKey=" + Redacted, "nested": []any{
+				map[string]any{"password": Redacted},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Redact(tt.command, tt.params)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Redact(%q, %v) = %v, want %v", tt.command, tt.params, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"mcp.azure/internal/audit"
+	"mcp.azure/internal/metadata"
+)
+
+// Supported values for the --output flag of the audit command.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type serverAuditFlags struct {
+	since   string
+	until   string
+	tool    string
+	command string
+	session string
+	outcome string
+	output  string
+}
+
+func newServerAuditCommand() *cobra.Command {
+	flags := &serverAuditFlags{}
+
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Query the audit log of commands dispatched to child tools",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.output != outputTable && flags.output != outputJSON {
+				return fmt.Errorf("unsupported output '%s', expected one of: table, json", flags.output)
+			}
+
+			now := time.Now()
+			since, err := parseAuditTime(flags.since, now)
+			if err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			until, err := parseAuditTime(flags.until, now)
+			if err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+
+			auditPath, err := auditLogPath()
+			if err != nil {
+				return err
+			}
+
+			entries, err := audit.Read(auditPath, audit.Filter{
+				Since:   since,
+				Until:   until,
+				Tool:    flags.tool,
+				Command: flags.command,
+				Session: flags.session,
+				Outcome: flags.outcome,
+			})
+			if err != nil {
+				return err
+			}
+
+			if flags.output == outputJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				for _, entry := range entries {
+					if err := encoder.Encode(entry); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			return printAuditTable(cmd, entries)
+		},
+	}
+
+	auditCmd.Flags().StringVar(&flags.since, "since", "24h", "Only show commands since this time, as a duration ago such as 2h or an RFC 3339 time")
+	auditCmd.Flags().StringVar(&flags.until, "until", "", "Only show commands until this time, as a duration ago such as 2h or an RFC 3339 time")
+	auditCmd.Flags().StringVar(&flags.tool, "tool", "", "Only show commands of this tool")
+	auditCmd.Flags().StringVar(&flags.command, "command", "", "Only show commands with this name")
+	auditCmd.Flags().StringVar(&flags.session, "session", "", "Only show commands of this client session")
+	auditCmd.Flags().StringVar(&flags.outcome, "outcome", "", "Only show commands with this outcome: success, error, cancelled or dry_run")
+	auditCmd.Flags().StringVar(&flags.output, "output", outputTable, "Output format: table or json (one entry per line)")
+
+	return auditCmd
+}
+
+// openAuditLog opens the audit log in the root server's config directory.
+func openAuditLog(options ...audit.Option) (*audit.Log, error) {
+	auditPath, err := auditLogPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return audit.Open(auditPath, options...)
+}
+
+func auditLogPath() (string, error) {
+	configDir, err := metadata.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, audit.FileName), nil
+}
+
+// parseAuditTime parses a duration before now, such as "2h", or an RFC 3339 time.
+// An empty value is the zero time, which does not limit the query.
+func parseAuditTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a duration such as 2h or an RFC 3339 time, got '%s'", value)
+	}
+
+	return t, nil
+}
+
+func printAuditTable(cmd *cobra.Command, entries []audit.Entry) error {
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "No audited commands found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tSESSION\tTOOL\tCOMMAND\tVERSION\tDURATION\tOUTCOME\tINTENT")
+	for _, e := range entries {
+		outcome := e.Outcome
+		if e.ErrorCode != "" {
+			outcome += " (" + e.ErrorCode + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Time.Local().Format(time.DateTime),
+			orDash(e.Session),
+			e.Tool,
+			e.Command,
+			orDash(e.Version),
+			(time.Duration(e.DurationMs) * time.Millisecond).String(),
+			outcome,
+			orDash(strings.Join(strings.Fields(e.Intent), " ")),
+		)
+	}
+
+	return w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
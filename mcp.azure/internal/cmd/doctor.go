@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"mcp.azure/internal/doctor"
+	"mcp.azure/internal/metadata"
+)
+
+type serverDoctorFlags struct {
+	timeout time.Duration
+	output  string
+}
+
+func newServerDoctorCommand() *cobra.Command {
+	flags := &serverDoctorFlags{}
+
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check azd, az, the extensions, credentials and every child tool server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.output != outputTable && flags.output != outputJSON {
+				return fmt.Errorf("unsupported output '%s', expected one of: table, json", flags.output)
+			}
+
+			allTools, checks := loadToolsForDoctor(cmd.Context())
+			report := doctor.New(allTools, doctor.WithTimeout(flags.timeout), doctor.WithChecks(checks...)).Run(cmd.Context())
+
+			if flags.output == outputJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return err
+				}
+			} else if err := report.WriteText(cmd.OutOrStdout()); err != nil {
+				return err
+			}
+
+			if !report.Healthy {
+				return fmt.Errorf("%d checks failed", report.Errors)
+			}
+			return nil
+		},
+	}
+
+	doctorCmd.Flags().DurationVar(&flags.timeout, "timeout", doctor.DefaultTimeout, "Timeout of each check, such as starting a child tool server")
+	doctorCmd.Flags().StringVar(&flags.output, "output", outputTable, "Output format: table or json")
+
+	return doctorCmd
+}
+
+// loadToolsForDoctor loads the tools like the server does, but reports failures as checks
+// so the other checks still run.
+func loadToolsForDoctor(ctx context.Context) ([]metadata.ToolMetadata, []doctor.Check) {
+	var checks []doctor.Check
+
+	azdTools, err := metadata.LoadAzdToolMetadata(ctx)
+	if err != nil {
+		checks = append(checks, doctor.Check{
+			Category: doctor.CategoryConfig,
+			Name:     "azd extensions",
+			Status:   doctor.StatusError,
+			Detail:   err.Error(),
+		})
+	}
+
+	externalTools, err := metadata.LoadExternalToolMetadata(ctx)
+	if err != nil {
+		checks = append(checks, doctor.Check{
+			Category: doctor.CategoryConfig,
+			Name:     "mcp.json",
+			Status:   doctor.StatusError,
+			Detail:   err.Error(),
+		})
+	}
+
+	allTools, duplicates := metadata.Merge(externalTools, azdTools)
+	checks = append(checks, doctor.DuplicateChecks(duplicates)...)
+	if untrusted, ok := metadata.UntrustedProjectRegistry(); ok {
+		checks = append(checks, doctor.Check{
+			Category: doctor.CategoryConfig,
+			Name:     "project mcp.json",
+			Status:   doctor.StatusWarning,
+			Detail:   untrusted.String(),
+		})
+	}
+
+	return allTools, checks
+}
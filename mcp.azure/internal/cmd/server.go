@@ -2,37 +2,118 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/mark3labs/mcp-go/client"
-	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/spf13/cobra"
 
+	"mcp.azure/internal/audit"
 	"mcp.azure/internal/metadata"
+	"mcp.azure/internal/pool"
+	"mcp.azure/internal/tools"
 )
 
-// Update toolClientCache to use map[string]*client.Client
-var toolClientCache = make(map[string]*client.Client)
+// Supported values for the --transport flag.
+const (
+	transportStdio = "stdio"
+	transportHTTP  = "http"
+	transportSSE   = "sse"
+)
+
+// Supported values for the --mode flag.
+const (
+	// modeSingle exposes only the root "azure" tool.
+	modeSingle = "single"
+	// modeFlat additionally exposes every child command as a "<tool>.<command>" tool.
+	modeFlat = "flat"
+)
+
+// serverTokenEnvVar names the environment variable holding the bearer token clients of the
+// http and sse transports have to send. Without it the server only listens on loopback addresses,
+// since anyone who can reach it drives Azure with the credentials of the host.
+const serverTokenEnvVar = "AZD_MCP_SERVER_TOKEN"
+
+// shutdownTimeout is how long in-flight requests get to finish once a shutdown signal is received.
+const shutdownTimeout = 30 * time.Second
+
+type serverStartFlags struct {
+	idleTimeout time.Duration
+	maxChildren int
+	mode        string
+	transport   string
+	listen      string
+	path        string
+	readOnly    bool
+	dryRun      bool
+
+	audit         bool
+	auditMaxSize  int
+	auditMaxFiles int
+}
 
 func newServerCommand() *cobra.Command {
 	serverGroup := &cobra.Command{
 		Use: "server",
 	}
 
+	flags := &serverStartFlags{}
+
 	startCmd := &cobra.Command{
 		Use: "start",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
+
+			switch flags.transport {
+			case transportStdio, transportHTTP, transportSSE:
+			default:
+				return fmt.Errorf("unsupported transport '%s', expected one of: stdio, http, sse", flags.transport)
+			}
+			if flags.mode != modeSingle && flags.mode != modeFlat {
+				return fmt.Errorf("unsupported mode '%s', expected one of: single, flat", flags.mode)
+			}
+			token := os.Getenv(serverTokenEnvVar)
+			if flags.transport != transportStdio && token == "" && !isLoopback(flags.listen) {
+				return fmt.Errorf(
+					"refusing to listen on %s without authentication, set %s to the bearer token clients have to send or listen on localhost",
+					flags.listen, serverTokenEnvVar,
+				)
+			}
+
+			// Every session gets its own child servers, they are stopped when the session ends.
+			clients := pool.NewSessions(
+				pool.WithIdleTimeout(flags.idleTimeout),
+				pool.WithMaxClients(flags.maxChildren),
+			)
+			defer clients.Close()
+
+			hooks := &server.Hooks{}
+			hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+				_ = clients.Remove(session.SessionID())
+			})
+
+			// Calls cancelled by the client are cancelled on the child servers too.
+			cancellations := tools.NewCancellations()
+			hooks.AddBeforeCallTool(cancellations.BeforeCallTool)
+			hooks.AddOnError(cancellations.OnError)
+
 			s := server.NewMCPServer(
 				"Azure",
 				"1.0.0",
 				server.WithToolCapabilities(true),
 				server.WithRecovery(),
 				server.WithLogging(),
-				server.WithPromptCapabilities(false),
-				server.WithResourceCapabilities(false, false),
+				server.WithHooks(hooks),
+				server.WithToolHandlerMiddleware(cancellations.Middleware),
+				server.WithPromptCapabilities(true),
+				server.WithResourceCapabilities(true, true),
 				server.WithInstructions(`
 					This server/tool provides real-time, programmatic access to all Azure products, services, and resources,
 					as well as all interactions with the Azure Developer CLI (azd).
@@ -55,154 +136,205 @@ func newServerCommand() *cobra.Command {
 				return err
 			}
 
-			allTools := append(azdTools, externalTools...)
-
-			// Build []mcp.Tool for server registration and a map for fast lookup
-			var childTools []mcp.Tool
-			toolMetadataMap := make(map[string]metadata.ToolMetadata)
-			for _, t := range allTools {
-				meta := t.Metadata()
-				childTools = append(childTools, meta)
-				toolMetadataMap[meta.Name] = t
+			// azd extensions take precedence over registered servers with the same name
+			allTools, duplicates := metadata.Merge(externalTools, azdTools)
+			for _, d := range duplicates {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", d)
+			}
+			if untrusted, ok := metadata.UntrustedProjectRegistry(); ok {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", untrusted)
 			}
 
-			azureTool := mcp.NewTool(
-				"azure",
-				mcp.WithDescription(`
-					This server/tool provides real-time, programmatic access to all Azure products, services, and resources,
-					as well as all interactions with the Azure Developer CLI (azd).
-					Use this tool for any Azure control plane or data plane operation, including resource management and automation.
-					To discover available capabilities, call the tool with the "learn" parameter to get a list of top-level tools.
-					To explore further, set "learn" and specify a tool name to retrieve supported commands and their parameters.
-					To execute an action, set the "tool", "command", and convert the users intent into the "parameters" based on the discovered schema.
-					Always use this tool for any Azure or "azd" related operation requiring up-to-date, dynamic, and interactive capabilities.
-				`),
-				mcp.WithString("intent",
-					mcp.Required(),
-					mcp.Description("The intent of the operation the user wants to perform against azure."),
-				),
-				mcp.WithString("tool",
-					mcp.Description("The azure tool to use to execute the operation."),
-				),
-				mcp.WithString("command",
-					mcp.Description("The command to execute against the specified tool."),
-				),
-				mcp.WithObject("parameters",
-					mcp.Description("The parameters to pass to the tool"),
-				),
-				mcp.WithBoolean("learn",
-					mcp.Description("To learn about the tool and its supported child tools and parameters."),
-					mcp.DefaultBool(false),
-				),
-			)
-
-			s.AddTool(azureTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-				toolName, hasToolName := request.GetArguments()["tool"].(string)
-
-				learn, ok := request.GetArguments()["learn"].(bool)
-				if ok && learn {
-					if hasToolName && toolName != "" {
-						tm, ok := toolMetadataMap[toolName]
-						if !ok {
-							return mcp.NewToolResultText(fmt.Sprintf(`
-								Tool %s not found
-								Run again with the "learn" argument and empty "tool" to get a list of available tools and their parameters.
-							`, toolName)), nil
-						}
-
-						// Caching at caller side
-						cacheKey := toolName
-						toolClient, ok := toolClientCache[cacheKey]
-						if !ok {
-							var err error
-							toolClient, err = tm.CreateClient(ctx)
-							if err != nil {
-								return mcp.NewToolResultText(fmt.Sprintf("Failed to start tool client: %v", err)), nil
-							}
-
-							toolClientCache[cacheKey] = toolClient
-						}
-
-						childTools, err := toolClient.ListTools(ctx, mcp.ListToolsRequest{})
-						if err != nil {
-							return nil, fmt.Errorf("failed to get child tools: %w", err)
-						}
-						toolsJson, err := json.MarshalIndent(childTools, "", "  ")
-						if err != nil {
-							return nil, fmt.Errorf("failed get get learn content: %w", err)
-						}
-						learnContent := fmt.Sprintf(`
-							Here are the available command and their parameters for '%s' tool.
-							If you do not find a suitable tool, run again with the "learn" argument and empty "tool" to get a list of available tools and their parameters.
-
-							%s
-						`, toolName, string(toolsJson))
-
-						return mcp.NewToolResultText(learnContent), nil
-					}
-
-					toolsJson, err := json.MarshalIndent(childTools, "", "  ")
-					if err != nil {
-						return nil, fmt.Errorf("failed get get learn content: %w", err)
-					}
-
-					return mcp.NewToolResultText(string(toolsJson)), nil
-				}
+			schemas, err := metadata.OpenSchemaCache()
+			if err != nil {
+				return err
+			}
 
-				commandName, hasCommandName := request.GetArguments()["command"].(string)
-				if !hasToolName || !hasCommandName {
-					return mcp.NewToolResultText(`
-						The "tool" and "command" parameters are required when not learning
-						Run again with the "learn" argument to get a list of available tools and their parameters.
-						To learn about a specific tool, use the "tool" argument with the name of the tool.
-					`), nil
-				}
-				tm, ok := toolMetadataMap[toolName]
-				if !ok {
-					return mcp.NewToolResultText(fmt.Sprintf(`
-						Tool %s not found
-						Run again with the "learn" argument and empty "tool" to get a list of available tools and their parameters.
-					`, toolName)), nil
+			options := []tools.Option{
+				tools.WithSchemaCache(schemas),
+				tools.WithDuplicates(duplicates),
+			}
+			if flags.readOnly {
+				options = append(options, tools.WithReadOnly())
+			}
+			if flags.dryRun {
+				options = append(options, tools.WithDryRun())
+			}
+			if flags.audit {
+				auditLog, err := openAuditLog(
+					audit.WithMaxSize(int64(flags.auditMaxSize)*1024*1024),
+					audit.WithMaxFiles(flags.auditMaxFiles),
+				)
+				if err != nil {
+					return err
 				}
+				options = append(options, tools.WithAuditLog(auditLog))
+			}
 
-				cacheKey := toolName
-				toolClient, ok := toolClientCache[cacheKey]
-				if !ok {
-					var err error
-					toolClient, err = tm.CreateClient(ctx)
-					if err != nil {
-						return mcp.NewToolResultText(fmt.Sprintf("Failed to start tool client: %v", err)), nil
-					}
-					toolClientCache[cacheKey] = toolClient
-				}
+			azureTool := tools.NewAzureTool(allTools, clients, options...)
+			hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+				azureTool.EndSession(session.SessionID())
+			})
+			s.AddNotificationHandler(metadata.MethodNotificationCancelled, cancellations.HandleCancelled)
+			s.AddTool(azureTool.Tool(), azureTool.Handle)
 
-				params := request.GetArguments()["parameters"]
-				childRequest := request
-				childRequest.Params.Name = commandName
-				childRequest.Params.Arguments = params
+			if flags.mode == modeFlat {
+				tools.NewFlatTools(azureTool, s).Preload()
+			}
 
-				toolCallResult, err := toolClient.CallTool(ctx, childRequest)
-				if err != nil {
-					return mcp.NewToolResultText(fmt.Sprintf(`
-						There was an error finding or calling tool and command.
-						Failed to call tool: %s, command: %s, Error: %v
+			tools.NewPrompts(azureTool, s).Register()
 
-						Run again with the "learn" argument and the "tool" name to get a list of available tools and their parameters.
-					`, toolName, commandName, err)), nil
-				}
-				return toolCallResult, nil
+			// Resource updates are only sent to the sessions subscribed to the resource.
+			subscriptions := tools.NewSubscriptions(s)
+			hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+				subscriptions.EndSession(session.SessionID())
 			})
 
+			// Resources and prompts of child tools are proxied under their tool name.
+			proxy := tools.NewChildProxy(azureTool, s, subscriptions)
+			proxy.Register()
+			clients.OnStart(proxy.ChildStarted)
+			clients.OnNotification(proxy.ChildNotification)
+
+			resources := tools.NewResources(azureTool, s, subscriptions)
+			resources.Register()
+
+			watchCtx, stopWatching := context.WithCancel(ctx)
+			defer stopWatching()
+			go resources.Watch(watchCtx)
+
 			// Start the server
-			if err := server.ServeStdio(s); err != nil {
-				fmt.Printf("Server error: %v\n", err)
+			if flags.transport == transportStdio {
+				if err := serveStdio(ctx, s, subscriptions); err != nil {
+					fmt.Printf("Server error: %v\n", err)
+				}
+
+				return nil
 			}
 
-			return nil
+			return serveHTTP(ctx, s, subscriptions, flags, token)
 		},
 	}
 
+	startCmd.Flags().DurationVar(&flags.idleTimeout, "idle-timeout", 10*time.Minute, "Stop each child tool server once idle for this long (0 to keep them running)")
+	startCmd.Flags().StringVar(&flags.mode, "mode", modeSingle, "Tool mode: single exposes only the azure tool, flat also exposes each child command as a <tool>.<command> tool")
+	startCmd.Flags().StringVar(&flags.transport, "transport", transportStdio, "Transport to serve the root server over: stdio, http or sse")
+	startCmd.Flags().StringVar(&flags.listen, "listen", "localhost:8080",
+		"Address to listen on for the http and sse transports. Clients can use the Azure credentials of the host, "+
+			"so addresses other than localhost require a bearer token in "+serverTokenEnvVar)
+	startCmd.Flags().StringVar(&flags.path, "path", "/mcp", "Endpoint path for the http transport, or base path for the sse transport")
+	startCmd.Flags().IntVar(&flags.maxChildren, "max-children", 0, "Maximum number of child tool servers running at once across all sessions (0 for no limit)")
+	startCmd.Flags().BoolVar(&flags.readOnly, "read-only", false, "Refuse child commands that are not known to only read state")
+	startCmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "Validate child commands and return what would be run instead of running them")
+	startCmd.Flags().BoolVar(&flags.audit, "audit", true, "Record every command dispatched to a child tool in the audit log")
+	startCmd.Flags().IntVar(&flags.auditMaxSize, "audit-max-size", audit.DefaultMaxSize/1024/1024, "Size in MB after which the audit log is rotated (0 to never rotate)")
+	startCmd.Flags().IntVar(&flags.auditMaxFiles, "audit-max-files", audit.DefaultMaxFiles, "Number of rotated audit log files to keep")
+
 	serverGroup.AddCommand(startCmd)
+	serverGroup.AddCommand(newServerAuditCommand())
+	serverGroup.AddCommand(newServerDoctorCommand())
+	serverGroup.AddCommand(newServerTrustCommand())
 
 	return serverGroup
 }
+
+// httpServer is implemented by the streamable HTTP and SSE servers.
+type httpServer interface {
+	Start(addr string) error
+	Shutdown(ctx context.Context) error
+}
+
+// stdioSessionID is the id of the single session of the stdio transport.
+const stdioSessionID = "stdio"
+
+// serveStdio serves the root server over stdin and stdout until stdin is closed, or SIGINT or
+// SIGTERM is received.
+func serveStdio(ctx context.Context, s *server.MCPServer, subscriptions *tools.Subscriptions) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return server.NewStdioServer(s).Listen(ctx, subscriptions.Reader(os.Stdin, stdioSessionID), os.Stdout)
+}
+
+// serveHTTP serves the root server over HTTP until SIGINT or SIGTERM is received,
+// then waits for in-flight requests to finish before returning. Requests without the bearer
+// token are refused when a token is set.
+func serveHTTP(ctx context.Context, s *server.MCPServer, subscriptions *tools.Subscriptions, flags *serverStartFlags, token string) error {
+	// The handler intercepts the resource subscription requests the server does not route.
+	listener := &http.Server{Addr: flags.listen}
+	var httpSrv httpServer
+	if flags.transport == transportSSE {
+		sseSrv := server.NewSSEServer(s, server.WithStaticBasePath(flags.path), server.WithHTTPServer(listener))
+		listener.Handler = subscriptions.Handler(sseSrv, func(r *http.Request) string {
+			return r.URL.Query().Get("sessionId")
+		})
+		httpSrv = sseSrv
+	} else {
+		streamableSrv := server.NewStreamableHTTPServer(s, server.WithEndpointPath(flags.path), server.WithStreamableHTTPServer(listener))
+		mux := http.NewServeMux()
+		mux.Handle(flags.path, subscriptions.Handler(streamableSrv, func(r *http.Request) string {
+			return r.Header.Get(server.HeaderKeySessionID)
+		}))
+		listener.Handler = mux
+		httpSrv = streamableSrv
+	}
+	if token != "" {
+		listener.Handler = requireToken(listener.Handler, token)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpSrv.Start(flags.listen)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving over %s on %s%s\n", flags.transport, flags.listen, flags.path)
+
+	select {
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("server error: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+
+	if err := httpSrv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down server: %w", err)
+	}
+
+	return nil
+}
+
+// isLoopback reports whether the listen address only accepts connections from the host.
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// requireToken refuses the requests that do not carry the bearer token.
+func requireToken(next http.Handler, token string) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"mcp.azure/internal/metadata"
+)
+
+type serverTrustFlags struct {
+	server string
+	remove bool
+}
+
+func newServerTrustCommand() *cobra.Command {
+	flags := &serverTrustFlags{}
+
+	trustCmd := &cobra.Command{
+		Use:   "trust",
+		Short: "Trust the mcp.json of the project in the current directory, or a stdio server, so it is loaded",
+		Long: `Trust the mcp.json next to the azure.yaml of the project in the current directory.
+Servers registered in a project registry run on this machine, so the registry is only loaded once it is trusted,
+and has to be trusted again whenever its content changes.
+
+With --server, approve starting the stdio server registered under that name instead. The server is started
+without asking until its command, arguments or environment change.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.server != "" {
+				return trustServer(cmd, flags)
+			}
+
+			if flags.remove {
+				path, err := metadata.DistrustProjectRegistry()
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s is no longer trusted\n", path)
+				return nil
+			}
+
+			path, err := metadata.TrustProjectRegistry()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is trusted until its content changes\n", path)
+			return nil
+		},
+	}
+
+	trustCmd.Flags().StringVar(&flags.server, "server", "", "Approve starting the stdio server registered under this name")
+	trustCmd.Flags().BoolVar(&flags.remove, "remove", false, "Stop trusting the project registry, or the server")
+
+	return trustCmd
+}
+
+func trustServer(cmd *cobra.Command, flags *serverTrustFlags) error {
+	if flags.remove {
+		if err := metadata.DistrustServer(flags.server); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Server %s is no longer approved\n", flags.server)
+		return nil
+	}
+
+	commandLine, err := metadata.TrustServer(cmd.Context(), flags.server)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Server %s is approved to run '%s' until its command changes\n", flags.server, commandLine)
+	return nil
+}
@@ -0,0 +1,172 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+
+	"mcp.azure/internal/metadata"
+)
+
+// checkBinary finds the binary on the PATH and reports its version.
+func checkBinary(ctx context.Context, name string, versionArgs ...string) Check {
+	check := Check{Category: CategoryBinary, Name: name}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		check.Status = StatusError
+		check.Detail = fmt.Sprintf("%s was not found on the PATH", name)
+		return check
+	}
+
+	out, err := exec.CommandContext(ctx, name, versionArgs...).Output()
+	if err != nil {
+		check.Status = StatusError
+		check.Detail = fmt.Sprintf("%s failed to report its version: %s", path, timeoutError(ctx, err))
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Version = binaryVersion(name, out)
+	check.Detail = path
+	return check
+}
+
+// binaryVersion reads the version from the JSON output of "azd version" or "az version",
+// falling back to the first line of the output.
+func binaryVersion(name string, out []byte) string {
+	var versions map[string]any
+	if err := json.Unmarshal(out, &versions); err == nil {
+		switch name {
+		case "azd":
+			if azd, ok := versions["azd"].(map[string]any); ok {
+				if version, ok := azd["version"].(string); ok {
+					return version
+				}
+			}
+		case "az":
+			if version, ok := versions["azure-cli"].(string); ok {
+				return version
+			}
+		}
+	}
+
+	return firstLine(string(out))
+}
+
+// checkAzdCredential checks that azd is signed in.
+func checkAzdCredential(ctx context.Context) Check {
+	check := Check{Category: CategoryCredential, Name: "azd"}
+	if _, err := exec.LookPath("azd"); err != nil {
+		check.Status = StatusSkipped
+		check.Detail = "azd was not found on the PATH"
+		return check
+	}
+
+	out, err := exec.CommandContext(ctx, "azd", "auth", "login", "--check-status", "--output", "json").Output()
+	var status struct {
+		Status    string `json:"status"`
+		ExpiresOn string `json:"expiresOn"`
+	}
+	if err != nil || json.Unmarshal(out, &status) != nil || status.Status != "success" {
+		check.Status = StatusError
+		check.Detail = `not signed in, run "azd auth login"`
+		if err != nil && ctx.Err() != nil {
+			check.Detail = timeoutError(ctx, err)
+		}
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Detail = "signed in"
+	if status.ExpiresOn != "" {
+		check.Detail += ", token expires " + status.ExpiresOn
+	}
+	return check
+}
+
+// checkAzCredential checks that the Azure CLI can acquire an access token.
+// The token itself is never reported.
+func checkAzCredential(ctx context.Context) Check {
+	check := Check{Category: CategoryCredential, Name: "az"}
+	if _, err := exec.LookPath("az"); err != nil {
+		check.Status = StatusSkipped
+		check.Detail = "az was not found on the PATH"
+		return check
+	}
+
+	out, err := exec.CommandContext(ctx, "az", "account", "get-access-token", "--output", "json").Output()
+	var token struct {
+		ExpiresOn    string `json:"expiresOn"`
+		Subscription string `json:"subscription"`
+		Tenant       string `json:"tenant"`
+	}
+	if err != nil || json.Unmarshal(out, &token) != nil {
+		check.Status = StatusError
+		check.Detail = `failed to acquire a token, run "az login"`
+		if err != nil && ctx.Err() != nil {
+			check.Detail = timeoutError(ctx, err)
+		}
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Detail = fmt.Sprintf("token acquired for subscription %s in tenant %s, expires %s", token.Subscription, token.Tenant, token.ExpiresOn)
+	return check
+}
+
+// checkToolCredential checks that the credentials of a server registered in mcp.json can be
+// acquired without signing in.
+func checkToolCredential(ctx context.Context, t *metadata.ExternalToolMetadata) Check {
+	check := Check{Category: CategoryCredential, Name: t.Metadata().Name}
+
+	description, err := t.CheckCredentials(ctx)
+	switch {
+	case description == "" && err == nil:
+		// The server needs no credentials, so there is nothing to report.
+		return Check{}
+	case err != nil:
+		check.Status = StatusError
+		check.Detail = description + ": " + timeoutError(ctx, err)
+	default:
+		check.Status = StatusOK
+		check.Detail = description + " acquired"
+	}
+
+	return check
+}
+
+// checkEndpoint checks that the URL of a remote server can be reached. Any HTTP response
+// counts, the server may well refuse a plain request without credentials.
+func checkEndpoint(ctx context.Context, t *metadata.ExternalToolMetadata) Check {
+	check := Check{Category: CategoryEndpoint, Name: t.Metadata().Name}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL(), nil)
+	if err != nil {
+		check.Status = StatusError
+		check.Detail = fmt.Sprintf("invalid URL %s: %v", t.URL(), err)
+		return check
+	}
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		check.Status = StatusError
+		check.Detail = fmt.Sprintf("%s is not reachable: %s", t.URL(), timeoutError(ctx, err))
+		return check
+	}
+	resp.Body.Close()
+
+	check.Status = StatusOK
+	check.Detail = fmt.Sprintf("%s responded with %s", t.URL(), resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		check.Detail += ", credentials are required"
+	case resp.StatusCode >= http.StatusInternalServerError:
+		check.Status = StatusWarning
+	}
+
+	return check
+}
@@ -0,0 +1,286 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp.azure/internal/metadata"
+)
+
+// DefaultTimeout bounds each check, such as starting a child server and listing its commands.
+const DefaultTimeout = 30 * time.Second
+
+// Categories of checks, in the order they are reported.
+const (
+	CategoryBinary     = "binary"
+	CategoryConfig     = "config"
+	CategoryExtension  = "extension"
+	CategoryCredential = "credential"
+	CategoryEndpoint   = "endpoint"
+	CategoryChild      = "child"
+)
+
+var categories = []string{CategoryBinary, CategoryConfig, CategoryExtension, CategoryCredential, CategoryEndpoint, CategoryChild}
+
+// Statuses of a check.
+const (
+	StatusOK      = "ok"
+	StatusWarning = "warning"
+	StatusError   = "error"
+	StatusSkipped = "skipped"
+)
+
+// Check is the result of a single check.
+type Check struct {
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	// Version and LatestVersion are set for binaries and extensions.
+	Version       string `json:"version,omitempty"`
+	LatestVersion string `json:"latestVersion,omitempty"`
+	// Commands is the number of commands listed by a child server.
+	Commands   int   `json:"commands,omitempty"`
+	DurationMs int64 `json:"durationMs,omitempty"`
+}
+
+// Report is the result of all checks.
+type Report struct {
+	Time    time.Time `json:"time"`
+	Healthy bool      `json:"healthy"`
+	Errors  int       `json:"errors"`
+	Checks  []Check   `json:"checks"`
+}
+
+// Doctor checks everything the root server depends on: the azd and az binaries, the
+// extensions, credentials, the endpoints of remote servers and every child server.
+type Doctor struct {
+	tools   []metadata.ToolMetadata
+	timeout time.Duration
+	checks  []Check
+}
+
+// Option configures a Doctor.
+type Option func(*Doctor)
+
+// WithTimeout bounds each check.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Doctor) {
+		d.timeout = timeout
+	}
+}
+
+// WithChecks adds checks made by the caller, such as failures to load the tools.
+func WithChecks(checks ...Check) Option {
+	return func(d *Doctor) {
+		d.checks = append(d.checks, checks...)
+	}
+}
+
+// New creates a doctor for the given tools.
+func New(tools []metadata.ToolMetadata, options ...Option) *Doctor {
+	d := &Doctor{
+		tools:   tools,
+		timeout: DefaultTimeout,
+	}
+	for _, opt := range options {
+		opt(d)
+	}
+
+	return d
+}
+
+// Run runs all checks in parallel and returns the report. Child servers are started as
+// they are: extensions are neither installed nor upgraded, and nobody is asked to sign in.
+func (d *Doctor) Run(ctx context.Context) *Report {
+	ctx = metadata.WithoutChanges(ctx)
+
+	checks := []func(context.Context) Check{
+		func(ctx context.Context) Check { return checkBinary(ctx, "azd", "version", "--output", "json") },
+		func(ctx context.Context) Check { return checkBinary(ctx, "az", "version", "--output", "json") },
+		checkAzdCredential,
+		checkAzCredential,
+	}
+	for _, tm := range d.tools {
+		switch t := tm.(type) {
+		case *metadata.AzdToolMetadata:
+			checks = append(checks, func(context.Context) Check { return checkExtension(t) })
+		case *metadata.ExternalToolMetadata:
+			checks = append(checks, func(ctx context.Context) Check { return checkToolCredential(ctx, t) })
+			if t.URL() != "" {
+				checks = append(checks, func(ctx context.Context) Check { return checkEndpoint(ctx, t) })
+			}
+		}
+		checks = append(checks, func(ctx context.Context) Check { return checkChild(ctx, tm) })
+	}
+
+	results := make([]Check, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, d.timeout)
+			defer cancel()
+
+			start := time.Now()
+			results[i] = check(ctx)
+			if results[i].DurationMs == 0 {
+				results[i].DurationMs = time.Since(start).Milliseconds()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := &Report{
+		Time:   time.Now().UTC(),
+		Checks: make([]Check, 0, len(d.checks)+len(results)),
+	}
+	all := append(append([]Check{}, d.checks...), results...)
+	for _, category := range categories {
+		for _, c := range all {
+			if c.Category != category || c.Status == "" {
+				continue
+			}
+			report.Checks = append(report.Checks, c)
+			if c.Status == StatusError {
+				report.Errors++
+			}
+		}
+	}
+	report.Healthy = report.Errors == 0
+
+	return report
+}
+
+// WriteText writes the report as a table grouped by category.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	category := ""
+	for _, c := range r.Checks {
+		if c.Category != category {
+			if category != "" {
+				fmt.Fprintln(tw)
+			}
+			category = c.Category
+			fmt.Fprintf(tw, "%s\n", strings.ToUpper(category))
+		}
+
+		version := c.Version
+		if c.LatestVersion != "" && c.LatestVersion != c.Version {
+			version += " (latest " + c.LatestVersion + ")"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", c.Status, c.Name, strings.TrimSpace(version), firstLine(c.Detail))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if r.Healthy {
+		_, err := fmt.Fprintln(w, "\nAll checks passed.")
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d checks failed.\n", r.Errors)
+	return err
+}
+
+// checkChild starts the child server, which initializes it, and lists its commands.
+func checkChild(ctx context.Context, tm metadata.ToolMetadata) Check {
+	check := Check{Category: CategoryChild, Name: tm.Metadata().Name}
+
+	start := time.Now()
+	c, err := tm.CreateClient(ctx)
+	if errors.Is(err, metadata.ErrNotInstalled) {
+		check.Status = StatusSkipped
+		check.Detail = "not installed, the extension is installed on first use"
+		return check
+	}
+	if errors.Is(err, metadata.ErrServerNotApproved) {
+		check.Status = StatusSkipped
+		check.Detail = err.Error()
+		return check
+	}
+	if err != nil {
+		check.Status = StatusError
+		check.Detail = "failed to start and initialize: " + timeoutError(ctx, err)
+		return check
+	}
+	defer c.Close()
+	initialized := time.Since(start)
+
+	result, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		check.Status = StatusError
+		check.Detail = "initialized, but failed to list commands: " + timeoutError(ctx, err)
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Commands = len(result.Tools)
+	check.Detail = fmt.Sprintf("initialized in %s, %d commands", initialized.Round(time.Millisecond), len(result.Tools))
+	if len(result.Tools) == 0 {
+		check.Status = StatusWarning
+		check.Detail = fmt.Sprintf("initialized in %s, but lists no commands", initialized.Round(time.Millisecond))
+	}
+
+	return check
+}
+
+// checkExtension reports the installed and latest versions of an azd extension.
+func checkExtension(t *metadata.AzdToolMetadata) Check {
+	info := t.VersionInfo()
+	check := Check{
+		Category:      CategoryExtension,
+		Name:          info.ID,
+		Status:        StatusOK,
+		Version:       info.Version,
+		LatestVersion: info.LatestVersion,
+	}
+
+	switch {
+	case info.Version == "":
+		check.Status = StatusWarning
+		check.Detail = "not installed, the extension is installed on first use"
+	case info.UpgradeAvailable != "":
+		check.Detail = fmt.Sprintf("%s is available, upgrade mode %s", info.UpgradeAvailable, info.Upgrade)
+	}
+
+	return check
+}
+
+func timeoutError(ctx context.Context, err error) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "timed out: " + err.Error()
+	}
+	return err.Error()
+}
+
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	return s
+}
+
+// DuplicateChecks reports tool registrations that were ignored in favor of another
+// registration with the same name.
+func DuplicateChecks(duplicates []metadata.Duplicate) []Check {
+	checks := make([]Check, 0, len(duplicates))
+	for _, d := range duplicates {
+		checks = append(checks, Check{
+			Category: CategoryConfig,
+			Name:     d.Name,
+			Status:   StatusWarning,
+			Detail:   d.String(),
+		})
+	}
+
+	return checks
+}
@@ -0,0 +1,118 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Subscription is an Azure subscription the Azure CLI is signed in to.
+type Subscription struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	TenantID  string `json:"tenantId"`
+	State     string `json:"state,omitempty"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+// AccountUser is the user or service principal the Azure CLI is signed in as.
+type AccountUser struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Account is the signed in Azure CLI account and its default subscription.
+type Account struct {
+	User         AccountUser  `json:"user"`
+	TenantID     string       `json:"tenantId"`
+	Environment  string       `json:"environment,omitempty"`
+	Subscription Subscription `json:"subscription"`
+}
+
+// azAccount is a subscription as reported by "az account show" and "az account list".
+type azAccount struct {
+	Subscription
+	EnvironmentName string      `json:"environmentName"`
+	User            AccountUser `json:"user"`
+}
+
+// LoadAccount returns the account the Azure CLI is signed in as and its default subscription.
+func LoadAccount(ctx context.Context) (*Account, error) {
+	var account azAccount
+	if err := runAz(ctx, &account, "account", "show", "--output", "json"); err != nil {
+		return nil, err
+	}
+
+	return &Account{
+		User:         account.User,
+		TenantID:     account.TenantID,
+		Environment:  account.EnvironmentName,
+		Subscription: account.Subscription,
+	}, nil
+}
+
+// LoadSubscriptions returns the subscriptions the Azure CLI is signed in to.
+func LoadSubscriptions(ctx context.Context) ([]Subscription, error) {
+	var accounts []azAccount
+	if err := runAz(ctx, &accounts, "account", "list", "--output", "json"); err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]Subscription, 0, len(accounts))
+	for _, account := range accounts {
+		subscriptions = append(subscriptions, account.Subscription)
+	}
+
+	return subscriptions, nil
+}
+
+func runAz(ctx context.Context, v any, args ...string) error {
+	command := "az " + strings.Join(args[:2], " ")
+	out, err := exec.CommandContext(ctx, "az", args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return fmt.Errorf("%s failed: %s", command, bytes.TrimSpace(exitErr.Stderr))
+		}
+		return fmt.Errorf("%s failed: %w", command, err)
+	}
+	if err := json.Unmarshal(out, v); err != nil {
+		return fmt.Errorf("failed to parse %s output: %w", command, err)
+	}
+
+	return nil
+}
+
+// ProfileSubscriptions reads the subscriptions from the Azure CLI profile without running az,
+// so the profile can be watched cheaply for a change of the default subscription.
+func ProfileSubscriptions() ([]Subscription, error) {
+	configDir := os.Getenv("AZURE_CONFIG_DIR")
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find Azure CLI config directory: %w", err)
+		}
+		configDir = filepath.Join(home, ".azure")
+	}
+
+	data, err := os.ReadFile(filepath.Join(configDir, "azureProfile.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var profile struct {
+		Subscriptions []Subscription `json:"subscriptions"`
+	}
+	// The Azure CLI writes the profile with a byte order mark.
+	if err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse Azure CLI profile: %w", err)
+	}
+
+	return profile.Subscriptions, nil
+}
@@ -0,0 +1,448 @@
+package metadata
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// Supported values for the "type" property of a server's "auth" block in mcp.json.
+const (
+	// AuthTypeHeader sends a static header whose value is read from an environment variable.
+	AuthTypeHeader = "header"
+	// AuthTypeAzure sends a Microsoft Entra ID bearer token for the configured scope.
+	AuthTypeAzure = "azure"
+	// AuthTypeOAuth uses the MCP authorization flow against the server's authorization server.
+	AuthTypeOAuth = "oauth"
+)
+
+const (
+	defaultAuthHeader       = "Authorization"
+	defaultOAuthRedirectURI = "http://localhost:8085/oauth/callback"
+	oauthLoginTimeout       = 5 * time.Minute
+)
+
+// mcpJsonAuth describes how the root authenticates to a remote MCP server.
+type mcpJsonAuth struct {
+	Type string `json:"type"`
+
+	// Header name and environment variable holding its value, for "header".
+	Header string `json:"header,omitempty"`
+	Env    string `json:"env,omitempty"`
+	// Prefix is prepended to the header value, e.g. "Bearer".
+	Prefix string `json:"prefix,omitempty"`
+
+	// Scope to request a token for, for "azure".
+	Scope string `json:"scope,omitempty"`
+
+	// OAuth client registration, for "oauth". ClientID may be empty when the
+	// authorization server supports dynamic client registration.
+	ClientID    string   `json:"clientId,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	RedirectURI string   `json:"redirectUri,omitempty"`
+}
+
+// ErrCredentialsUnavailable is returned when the credentials an "auth" block declares cannot
+// be acquired, such as an unset environment variable or an expired Azure sign-in. The request
+// is not sent without them.
+var ErrCredentialsUnavailable = errors.New("credentials unavailable")
+
+// azureCredential is shared by all servers using "azure" auth so tokens are cached once.
+var azureCredential = sync.OnceValues(func() (azcore.TokenCredential, error) {
+	return azidentity.NewDefaultAzureCredential(nil)
+})
+
+// httpAuth holds the resolved credentials for an HTTP based transport.
+type httpAuth struct {
+	// header returns the name and value of the credential header of a request.
+	header func(ctx context.Context) (string, string, error)
+	oauth  *transport.OAuthConfig
+}
+
+// headerTransport sets the credential header on every request. A request whose credential
+// cannot be acquired fails instead of being sent without it.
+type headerTransport struct {
+	header func(ctx context.Context) (string, string, error)
+	next   http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	name, value, err := t.header(request.Context())
+	if err != nil {
+		if request.Body != nil {
+			_ = request.Body.Close()
+		}
+		return nil, err
+	}
+
+	request = request.Clone(request.Context())
+	request.Header.Set(name, value)
+	return t.next.RoundTrip(request)
+}
+
+// resolve validates the auth configuration and returns how credentials are attached.
+// Header values are resolved on every request so rotated secrets and refreshed
+// tokens are picked up without restarting the client. Credentials that cannot be acquired
+// fail with ErrCredentialsUnavailable.
+func (a *mcpJsonAuth) resolve(ctx context.Context, toolName string, serverURL string) (*httpAuth, error) {
+	if a == nil {
+		return &httpAuth{}, nil
+	}
+
+	switch a.Type {
+	case AuthTypeHeader:
+		if a.Env == "" {
+			return nil, fmt.Errorf("missing 'auth.env' property for tool %s in mcp.json", toolName)
+		}
+		if _, err := a.headerValue(); err != nil {
+			return nil, fmt.Errorf("failed to get credentials for tool %s: %w", toolName, err)
+		}
+
+		return &httpAuth{
+			header: func(context.Context) (string, string, error) {
+				value, err := a.headerValue()
+				if err != nil {
+					return "", "", fmt.Errorf("failed to get credentials for tool %s: %w", toolName, err)
+				}
+				return a.headerName(), value, nil
+			},
+		}, nil
+
+	case AuthTypeAzure:
+		if a.Scope == "" {
+			return nil, fmt.Errorf("missing 'auth.scope' property for tool %s in mcp.json", toolName)
+		}
+
+		// Acquire a token up front so credential problems are reported when the
+		// client starts instead of as an opaque 401 from the server.
+		if _, err := a.azureToken(ctx); err != nil {
+			return nil, fmt.Errorf("failed to acquire Azure token for tool %s: %w", toolName, err)
+		}
+
+		return &httpAuth{
+			header: func(ctx context.Context) (string, string, error) {
+				token, err := a.azureToken(ctx)
+				if err != nil {
+					return "", "", fmt.Errorf("failed to acquire Azure token for tool %s: %w", toolName, err)
+				}
+				return defaultAuthHeader, "Bearer " + token, nil
+			},
+		}, nil
+
+	case AuthTypeOAuth:
+		tokenStore, err := newFileTokenStore(toolName, serverURL, a.ClientID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &httpAuth{
+			oauth: &transport.OAuthConfig{
+				ClientID:    a.ClientID,
+				RedirectURI: a.redirectURI(),
+				Scopes:      a.Scopes,
+				TokenStore:  tokenStore,
+				PKCEEnabled: true,
+			},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported auth type '%s' for tool %s in mcp.json", a.Type, toolName)
+	}
+}
+
+// streamableHTTPOptions returns the credentials as streamable HTTP transport options.
+func (h *httpAuth) streamableHTTPOptions() []transport.StreamableHTTPCOption {
+	var options []transport.StreamableHTTPCOption
+	if h.header != nil {
+		options = append(options, transport.WithHTTPBasicClient(h.httpClient()))
+	}
+	if h.oauth != nil {
+		options = append(options, transport.WithHTTPOAuth(*h.oauth))
+	}
+	return options
+}
+
+// httpClient returns a client that sets the credential header on every request.
+func (h *httpAuth) httpClient() *http.Client {
+	return &http.Client{
+		Transport: &headerTransport{header: h.header, next: http.DefaultTransport},
+	}
+}
+
+// sseOptions returns the credentials as SSE transport options.
+func (h *httpAuth) sseOptions() []transport.ClientOption {
+	var options []transport.ClientOption
+	if h.header != nil {
+		options = append(options, transport.WithHTTPClient(h.httpClient()))
+	}
+	if h.oauth != nil {
+		options = append(options, transport.WithOAuth(*h.oauth))
+	}
+	return options
+}
+
+// check reports whether credentials can be acquired without signing in, and describes them.
+// The description is empty when the server needs no credentials.
+func (a *mcpJsonAuth) check(ctx context.Context, toolName string, serverURL string) (string, error) {
+	if a == nil {
+		return "", nil
+	}
+
+	switch a.Type {
+	case AuthTypeHeader:
+		_, err := a.headerValue()
+		return fmt.Sprintf("%s header from %s", a.headerName(), a.Env), err
+
+	case AuthTypeAzure:
+		_, err := a.azureToken(ctx)
+		return fmt.Sprintf("Azure token for %s", a.Scope), err
+
+	case AuthTypeOAuth:
+		tokenStore, err := newFileTokenStore(toolName, serverURL, a.ClientID)
+		if err != nil {
+			return "OAuth token", err
+		}
+		token, err := tokenStore.GetToken(ctx)
+		if errors.Is(err, transport.ErrNoToken) {
+			return "OAuth token", fmt.Errorf("not signed in, the user is asked to sign in on the first call of tool %s", toolName)
+		}
+		if err != nil {
+			return "OAuth token", err
+		}
+		if token.IsExpired() && token.RefreshToken == "" {
+			return "OAuth token", fmt.Errorf("the token expired, the user is asked to sign in again on the next call of tool %s", toolName)
+		}
+		return "OAuth token", nil
+
+	default:
+		return "", fmt.Errorf("unsupported auth type '%s' for tool %s in mcp.json", a.Type, toolName)
+	}
+}
+
+func (a *mcpJsonAuth) headerName() string {
+	if a.Header == "" {
+		return defaultAuthHeader
+	}
+	return a.Header
+}
+
+func (a *mcpJsonAuth) headerValue() (string, error) {
+	value := os.Getenv(a.Env)
+	if value == "" {
+		return "", fmt.Errorf("%w: environment variable %s is not set", ErrCredentialsUnavailable, a.Env)
+	}
+	if a.Prefix != "" {
+		value = a.Prefix + " " + value
+	}
+	return value, nil
+}
+
+func (a *mcpJsonAuth) azureToken(ctx context.Context) (string, error) {
+	credential, err := azureCredential()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCredentialsUnavailable, err)
+	}
+
+	token, err := credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{a.Scope}})
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCredentialsUnavailable, err)
+	}
+
+	return token.Token, nil
+}
+
+func (a *mcpJsonAuth) redirectURI() string {
+	if a.RedirectURI == "" {
+		return defaultOAuthRedirectURI
+	}
+	return a.RedirectURI
+}
+
+// authorize runs the interactive OAuth authorization code flow with PKCE.
+// The user is sent to the authorization server in a browser and the code is
+// received on a local callback listener at the redirect URI.
+func (a *mcpJsonAuth) authorize(ctx context.Context, toolName string, authErr error) error {
+	handler := client.GetOAuthHandler(authErr)
+	if handler == nil {
+		return authErr
+	}
+	if withoutChanges(ctx) {
+		return fmt.Errorf("sign-in required for tool %s: %w", toolName, authErr)
+	}
+
+	redirect, err := url.Parse(a.redirectURI())
+	if err != nil {
+		return fmt.Errorf("invalid 'auth.redirectUri' for tool %s: %w", toolName, err)
+	}
+
+	if handler.GetClientID() == "" {
+		if err := handler.RegisterClient(ctx, "mcp.azure"); err != nil {
+			return fmt.Errorf("failed to register OAuth client for tool %s: %w", toolName, err)
+		}
+	}
+
+	codeVerifier, err := client.GenerateCodeVerifier()
+	if err != nil {
+		return err
+	}
+	state, err := client.GenerateState()
+	if err != nil {
+		return err
+	}
+
+	authURL, err := handler.GetAuthorizationURL(ctx, state, client.GenerateCodeChallenge(codeVerifier))
+	if err != nil {
+		return fmt.Errorf("failed to get OAuth authorization URL for tool %s: %w", toolName, err)
+	}
+
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return fmt.Errorf("failed to listen for OAuth callback on %s: %w", redirect.Host, err)
+	}
+
+	callback := make(chan url.Values, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(redirect.Path, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case callback <- r.URL.Query():
+		default:
+		}
+		fmt.Fprintln(w, "Authorization complete. You can close this window and return to your agent.")
+	})
+
+	callbackServer := &http.Server{Handler: mux}
+	go func() {
+		_ = callbackServer.Serve(listener)
+	}()
+	defer callbackServer.Close()
+
+	// stdout carries the MCP protocol, so the URL is only written to stderr.
+	fmt.Fprintf(os.Stderr, "Sign in to %s by visiting: %s\n", toolName, authURL)
+	_ = openBrowser(authURL)
+
+	ctx, cancel := context.WithTimeout(ctx, oauthLoginTimeout)
+	defer cancel()
+
+	var params url.Values
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for OAuth authorization for tool %s", toolName)
+	case params = <-callback:
+	}
+
+	if params.Get("error") != "" {
+		return fmt.Errorf("OAuth authorization for tool %s failed: %s", toolName, params.Get("error"))
+	}
+	if params.Get("state") != state {
+		return fmt.Errorf("OAuth authorization for tool %s failed: state mismatch", toolName)
+	}
+
+	return handler.ProcessAuthorizationResponse(ctx, params.Get("code"), state, codeVerifier)
+}
+
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	case "darwin":
+		cmd = exec.Command("open", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
+
+// fileTokenStore persists OAuth tokens under the azd config directory so the
+// user does not have to sign in again every time the root server starts.
+//
+// Tokens are stored per server URL and client ID, not only per tool name, so a registry that
+// registers another server under the same name never gets the token of the user's server. The
+// authorization server that issues the token is discovered from the server URL.
+type fileTokenStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func newFileTokenStore(toolName string, serverURL string, clientID string) (*fileTokenStore, error) {
+	// The name is checked again so a token can never be written outside the token directory.
+	if !toolNamePattern.MatchString(toolName) {
+		return nil, fmt.Errorf("invalid tool name '%s' for OAuth token", toolName)
+	}
+
+	configDir, err := azdConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileTokenStore{
+		path: filepath.Join(configDir, "mcp.azure", "tokens", toolName+"-"+tokenKey(serverURL, clientID)+".json"),
+	}, nil
+}
+
+// tokenKey identifies the server and client registration a token is issued for.
+func tokenKey(serverURL string, clientID string) string {
+	sum := sha256.Sum256([]byte(serverURL + "\n" + clientID))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (s *fileTokenStore) GetToken(ctx context.Context) (*transport.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, transport.ErrNoToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OAuth token: %w", err)
+	}
+
+	var token transport.Token
+	if err := json.Unmarshal(content, &token); err != nil {
+		return nil, transport.ErrNoToken
+	}
+
+	return &token, nil
+}
+
+func (s *fileTokenStore) SaveToken(ctx context.Context, token *transport.Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal OAuth token: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create OAuth token directory: %w", err)
+	}
+
+	return os.WriteFile(s.path, content, 0o600)
+}
@@ -2,12 +2,11 @@ package metadata
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os/exec"
 	"strings"
+	"sync"
 
-	"github.com/Masterminds/semver/v3"
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -15,6 +14,17 @@ import (
 // AzdToolMetadata implements ToolMetadata for azd extensions.
 type AzdToolMetadata struct {
 	Ext mcpExtensionMetadata
+
+	// mu guards Ext, which is updated after the extension is installed or upgraded.
+	mu sync.RWMutex
+	// ensureMu serializes installs and upgrades of the extension, which is shared by the pools
+	// of all sessions.
+	ensureMu sync.Mutex
+
+	// pin is the version policy of the extension.
+	pin extensionPin
+	// install decides whether the extension may be installed.
+	install installPolicy
 }
 
 // mcpExtensionMetadata holds azd extension metadata fields.
@@ -22,6 +32,7 @@ type mcpExtensionMetadata struct {
 	ID            string   `json:"id"`
 	Description   string   `json:"description"`
 	Namespace     string   `json:"namespace"`
+	Source        string   `json:"source"`
 	Version       string   `json:"version"`
 	LatestVersion string   `json:"latestVersion"`
 	Installed     bool     `json:"installed"`
@@ -29,34 +40,37 @@ type mcpExtensionMetadata struct {
 }
 
 func (a *AzdToolMetadata) Metadata() mcp.Tool {
-	name := strings.TrimPrefix(a.Ext.ID, "mcp.")
-	return mcp.NewTool(name, mcp.WithDescription(a.Ext.Description))
+	ext := a.extension()
+	name := strings.TrimPrefix(ext.ID, "mcp.")
+	return mcp.NewTool(name, mcp.WithDescription(ext.Description))
+}
+
+func (a *AzdToolMetadata) Source() string {
+	return "azd extension " + a.extension().ID
+}
+
+// extension returns a snapshot of the extension metadata.
+func (a *AzdToolMetadata) extension() mcpExtensionMetadata {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.Ext
 }
 
 func (a *AzdToolMetadata) CreateClient(ctx context.Context) (*client.Client, error) {
-	if a.Ext.Installed {
-		if a.Ext.LatestVersion != a.Ext.Version {
-			currentVer, currentVerErr := semver.NewVersion(a.Ext.Version)
-			latestVer, latestVerErr := semver.NewVersion(a.Ext.LatestVersion)
-			if currentVerErr == nil && latestVerErr == nil && latestVer.GreaterThan(currentVer) {
-				upgradeCmd := exec.Command("azd", "ext", "upgrade", a.Ext.ID)
-				upgradeOut, err := upgradeCmd.CombinedOutput()
-				if err != nil {
-					return nil, fmt.Errorf("failed to upgrade extension %s: %w\n%s", a.Ext.ID, err, string(upgradeOut))
-				}
-			}
-		}
-	} else {
-		installCmd := exec.Command("azd", "ext", "install", a.Ext.ID)
-		installOut, err := installCmd.CombinedOutput()
-		if err != nil {
-			return nil, fmt.Errorf("failed to install extension %s: %w\n%s", a.Ext.ID, err, string(installOut))
+	ext := a.extension()
+	if !withoutChanges(ctx) {
+		if err := a.ensureVersion(ctx); err != nil {
+			return nil, err
 		}
+		ext = a.extension()
+	} else if !ext.Installed {
+		return nil, fmt.Errorf("%w: %s", ErrNotInstalled, ext.ID)
 	}
 
-	nsParts := strings.Split(a.Ext.Namespace, ".")
+	nsParts := strings.Split(ext.Namespace, ".")
 	if len(nsParts) < 2 {
-		return nil, fmt.Errorf("invalid namespace for extension: %s", a.Ext.Namespace)
+		return nil, fmt.Errorf("invalid namespace for extension: %s", ext.Namespace)
 	}
 	args := append([]string{}, nsParts...)
 	args = append(args, "server", "start")
@@ -68,26 +82,95 @@ func (a *AzdToolMetadata) CreateClient(ctx context.Context) (*client.Client, err
 		Version: "1.0.0",
 	}
 
-	mcpClient, err := client.NewStdioMCPClient("azd", nil, args...)
+	mcpClient, err := newStdioClient(ctx, "azd", nil, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start MCP client for %s: %w", a.Ext.ID, err)
+		return nil, fmt.Errorf("failed to start MCP client for %s: %w", ext.ID, err)
 	}
 	if _, err := mcpClient.Initialize(ctx, initRequest); err != nil {
-		return nil, fmt.Errorf("failed to initialize Stdio MCP client for %s: %w", a.Ext.ID, err)
+		return nil, fmt.Errorf("failed to initialize Stdio MCP client for %s: %w", ext.ID, err)
 	}
 	return mcpClient, nil
 }
 
-// Loads azd extension tools as ToolMetadata.
+// ensureVersion installs the extension, or moves it to another version, as its policy requires.
+// Concurrent callers wait for the one installing or upgrading, then find the extension up to date.
+func (a *AzdToolMetadata) ensureVersion(ctx context.Context) error {
+	a.ensureMu.Lock()
+	defer a.ensureMu.Unlock()
+
+	ext := a.extension()
+	version, err := a.resolveVersion(ctx, ext)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !ext.Installed:
+		if err := a.approveInstall(ctx, ext, version); err != nil {
+			return err
+		}
+
+		args := []string{"ext", "install", ext.ID}
+		if version != "" {
+			args = append(args, "--version", version)
+		}
+		installCmd := exec.CommandContext(ctx, "azd", args...)
+		installOut, err := installCmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to install extension %s: %w\n%s", ext.ID, err, string(installOut))
+		}
+		if version == "" {
+			version = ext.LatestVersion
+		}
+		a.setInstalledVersion(version)
+
+	case version != "" && version != ext.Version:
+		args := []string{"ext", "upgrade", ext.ID, "--version", version}
+		if isDowngrade(ext.Version, version) {
+			// Only a forced install moves an extension back to an older version.
+			args = []string{"ext", "install", ext.ID, "--version", version, "--force"}
+		}
+		upgradeCmd := exec.CommandContext(ctx, "azd", args...)
+		upgradeOut, err := upgradeCmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to upgrade extension %s to %s: %w\n%s", ext.ID, version, err, string(upgradeOut))
+		}
+		a.setInstalledVersion(version)
+	}
+
+	return nil
+}
+
+// UpdateInstalled records the installed version of the extension as azd reports it, which
+// changes when the extension is installed, upgraded or uninstalled outside the server.
+// An empty version means the extension is not installed.
+func (a *AzdToolMetadata) UpdateInstalled(version string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.Ext.Installed = version != ""
+	a.Ext.Version = version
+}
+
+// setInstalledVersion records the version azd installed so cached schemas are keyed correctly.
+func (a *AzdToolMetadata) setInstalledVersion(version string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.Ext.Installed = true
+	a.Ext.Version = version
+}
+
+// Loads azd extension tools as ToolMetadata, from the extension cache when it is still valid.
 func LoadAzdToolMetadata(ctx context.Context) ([]ToolMetadata, error) {
-	extCmd := exec.Command("azd", "ext", "list", "--tags", "azure,mcp", "--output", "json")
-	extOut, err := extCmd.CombinedOutput()
+	extList, err := listExtensions(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get extension metadata: %w\n%s", err, string(extOut))
+		return nil, err
 	}
-	var extList []mcpExtensionMetadata
-	if err := json.Unmarshal(extOut, &extList); err != nil {
-		return nil, fmt.Errorf("failed to parse extension metadata: %w", err)
+
+	policy, err := LoadExtensionPolicy()
+	if err != nil {
+		return nil, err
 	}
 
 	var result []ToolMetadata
@@ -95,7 +178,11 @@ func LoadAzdToolMetadata(ctx context.Context) ([]ToolMetadata, error) {
 		if ext.ID == "mcp.azure" {
 			continue // skip self
 		}
-		result = append(result, &AzdToolMetadata{Ext: ext})
+		result = append(result, &AzdToolMetadata{
+			Ext:     ext,
+			pin:     policy.pin(ext.ID),
+			install: policy.installPolicy(),
+		})
 	}
 
 	return result, nil
@@ -0,0 +1,242 @@
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// schemaCacheVersion is bumped whenever the on-disk cache format changes.
+// Caches written with a different version are discarded.
+const schemaCacheVersion = 1
+
+// SchemaCache persists the commands, prompts and resources exposed by child tools so
+// "learn" can be answered, and prompts and resources listed, without starting the child server.
+// Entries are keyed by extension ID and version and are only used while the
+// extension's installed and latest versions are unchanged. Servers sharing the cache re-read
+// it before every update, so they keep the entries the others wrote.
+type SchemaCache struct {
+	path string
+	mu   sync.Mutex
+	data schemaCacheFile
+}
+
+type schemaCacheFile struct {
+	Version int                         `json:"version"`
+	Tools   map[string]schemaCacheEntry `json:"tools"`
+}
+
+type schemaCacheEntry struct {
+	ID            string     `json:"id"`
+	Version       string     `json:"version"`
+	LatestVersion string     `json:"latestVersion"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	Tools         []mcp.Tool `json:"tools"`
+	Catalog       *Catalog   `json:"catalog,omitempty"`
+}
+
+// Catalog holds the prompts and resources a child tool exposes besides its commands.
+type Catalog struct {
+	Prompts   []mcp.Prompt   `json:"prompts"`
+	Resources []mcp.Resource `json:"resources"`
+}
+
+// OpenSchemaCache loads the schema cache from the azd config directory.
+// A missing, unreadable or outdated cache file results in an empty cache.
+func OpenSchemaCache() (*SchemaCache, error) {
+	configDir, err := azdConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &SchemaCache{
+		path: filepath.Join(configDir, "mcp.azure", "schemas.json"),
+		data: schemaCacheFile{
+			Version: schemaCacheVersion,
+			Tools:   make(map[string]schemaCacheEntry),
+		},
+	}
+
+	if err := cache.loadLocked(); err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// loadLocked replaces the cache with the entries on disk, which other servers sharing the
+// cache may have added since it was last read. A missing, corrupt or outdated file is ignored.
+func (c *SchemaCache) loadLocked() error {
+	content, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read schema cache: %w", err)
+	}
+
+	var data schemaCacheFile
+	if err := json.Unmarshal(content, &data); err != nil || data.Version != schemaCacheVersion {
+		// Corrupt or outdated caches are rebuilt on demand.
+		return nil
+	}
+	if data.Tools != nil {
+		c.data = data
+	}
+
+	return nil
+}
+
+// Tools returns the cached commands for the tool if they are still valid.
+func (c *SchemaCache) Tools(tm ToolMetadata) ([]mcp.Tool, bool) {
+	ext, ok := cacheableExtension(tm)
+	if c == nil || !ok {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.data.Tools[schemaCacheKey(ext)]
+	if !ok || entry.LatestVersion != ext.LatestVersion || entry.Tools == nil {
+		return nil, false
+	}
+
+	return entry.Tools, true
+}
+
+// Catalog returns the cached prompts and resources for the tool if they are still valid.
+func (c *SchemaCache) Catalog(tm ToolMetadata) (Catalog, bool) {
+	ext, ok := cacheableExtension(tm)
+	if c == nil || !ok {
+		return Catalog{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.data.Tools[schemaCacheKey(ext)]
+	if !ok || entry.LatestVersion != ext.LatestVersion || entry.Catalog == nil {
+		return Catalog{}, false
+	}
+
+	return *entry.Catalog, true
+}
+
+// SetTools stores the commands for the tool and writes the cache to disk.
+// Entries for other versions of the same extension are removed.
+func (c *SchemaCache) SetTools(tm ToolMetadata, tools []mcp.Tool) error {
+	ext, ok := cacheableExtension(tm)
+	if c == nil || !ok {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if tools == nil {
+		tools = []mcp.Tool{}
+	}
+	_ = c.loadLocked()
+	entry := c.entryLocked(ext)
+	entry.Tools = tools
+	c.data.Tools[schemaCacheKey(ext)] = entry
+
+	return c.saveLocked()
+}
+
+// SetCatalog stores the prompts and resources for the tool and writes the cache to disk.
+func (c *SchemaCache) SetCatalog(tm ToolMetadata, catalog Catalog) error {
+	ext, ok := cacheableExtension(tm)
+	if c == nil || !ok {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ = c.loadLocked()
+	entry := c.entryLocked(ext)
+	entry.Catalog = &catalog
+	c.data.Tools[schemaCacheKey(ext)] = entry
+
+	return c.saveLocked()
+}
+
+// entryLocked returns the entry for the installed version of the extension to update.
+// Entries for other versions of the same extension are removed.
+func (c *SchemaCache) entryLocked(ext mcpExtensionMetadata) schemaCacheEntry {
+	key := schemaCacheKey(ext)
+	entry, ok := c.data.Tools[key]
+	if !ok || entry.LatestVersion != ext.LatestVersion {
+		entry = schemaCacheEntry{ID: ext.ID, Version: ext.Version, LatestVersion: ext.LatestVersion}
+	}
+	entry.UpdatedAt = time.Now().UTC()
+
+	for k, e := range c.data.Tools {
+		if e.ID == ext.ID && k != key {
+			delete(c.data.Tools, k)
+		}
+	}
+
+	return entry
+}
+
+// saveLocked writes the cache atomically so concurrent servers never read a partial file.
+func (c *SchemaCache) saveLocked() error {
+	if err := writeFileAtomic(c.path, c.data); err != nil {
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+
+	return nil
+}
+
+// cacheableExtension returns the azd extension metadata for tools whose schemas can be cached.
+// Only azd extensions carry a version, so external servers are always queried live.
+func cacheableExtension(tm ToolMetadata) (mcpExtensionMetadata, bool) {
+	azdTool, ok := tm.(*AzdToolMetadata)
+	if !ok {
+		return mcpExtensionMetadata{}, false
+	}
+
+	ext := azdTool.extension()
+	if ext.Version == "" {
+		return mcpExtensionMetadata{}, false
+	}
+
+	return ext, true
+}
+
+func schemaCacheKey(ext mcpExtensionMetadata) string {
+	return ext.ID + "@" + ext.Version
+}
+
+// azdConfigDir returns the azd configuration directory, honoring AZD_CONFIG_DIR.
+func azdConfigDir() (string, error) {
+	if dir := os.Getenv("AZD_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find azd config directory: %w", err)
+	}
+
+	return filepath.Join(home, ".azd"), nil
+}
+
+// ConfigDir returns the directory the root server keeps its files in, within the azd configuration directory.
+func ConfigDir() (string, error) {
+	configDir, err := azdConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "mcp.azure"), nil
+}
@@ -0,0 +1,70 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func azdTool(id string, version string) *AzdToolMetadata {
+	return &AzdToolMetadata{Ext: mcpExtensionMetadata{ID: id, Version: version, LatestVersion: version}}
+}
+
+func TestSchemaCacheKeepsEntriesOfOtherServers(t *testing.T) {
+	t.Setenv("AZD_CONFIG_DIR", t.TempDir())
+
+	// Two servers open the shared cache before either of them wrote to it.
+	first, err := OpenSchemaCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := OpenSchemaCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := azdTool("mcp.storage", "1.0.0")
+	keyvault := azdTool("mcp.keyvault", "1.0.0")
+	if err := first.SetTools(storage, []mcp.Tool{{Name: "list-accounts"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.SetTools(keyvault, []mcp.Tool{{Name: "list-keyvaults"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenSchemaCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tm := range []*AzdToolMetadata{storage, keyvault} {
+		if _, ok := reopened.Tools(tm); !ok {
+			t.Errorf("commands of %s are not cached", tm.Ext.ID)
+		}
+	}
+}
+
+func TestSchemaCacheDropsOtherVersions(t *testing.T) {
+	t.Setenv("AZD_CONFIG_DIR", t.TempDir())
+
+	cache, err := OpenSchemaCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := azdTool("mcp.storage", "1.0.0")
+	upgraded := azdTool("mcp.storage", "1.1.0")
+	if err := cache.SetTools(old, []mcp.Tool{{Name: "list-accounts"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.SetTools(upgraded, []mcp.Tool{{Name: "list-accounts"}, {Name: "create-account"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cache.Tools(old); ok {
+		t.Error("commands of the previous version are still cached")
+	}
+	tools, ok := cache.Tools(upgraded)
+	if !ok || len(tools) != 2 {
+		t.Errorf("Tools() = %v, %v, want the 2 commands of the upgraded version", tools, ok)
+	}
+}
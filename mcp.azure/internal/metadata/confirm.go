@@ -0,0 +1,43 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoConfirm is returned when there is no way to ask the user for confirmation.
+var ErrNoConfirm = errors.New("no way to ask the user for confirmation")
+
+// ConfirmFunc asks the user to confirm an action and reports whether they agreed.
+type ConfirmFunc func(ctx context.Context, message string) (bool, error)
+
+type confirmKey struct{}
+
+// WithConfirm returns a context through which client creation can ask the user for
+// confirmation, such as before upgrading an extension.
+func WithConfirm(ctx context.Context, confirm ConfirmFunc) context.Context {
+	return context.WithValue(ctx, confirmKey{}, confirm)
+}
+
+// confirm asks the user through the ConfirmFunc of the context.
+func confirm(ctx context.Context, message string) (bool, error) {
+	fn, ok := ctx.Value(confirmKey{}).(ConfirmFunc)
+	if !ok {
+		return false, ErrNoConfirm
+	}
+	return fn(ctx, message)
+}
+
+type withoutChangesKey struct{}
+
+// WithoutChanges returns a context in which client creation neither changes the machine nor
+// involves the user: extensions are not installed or upgraded and OAuth sign-in is not started.
+// It is used to diagnose the tools as they are.
+func WithoutChanges(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutChangesKey{}, true)
+}
+
+func withoutChanges(ctx context.Context) bool {
+	without, _ := ctx.Value(withoutChangesKey{}).(bool)
+	return without
+}
@@ -0,0 +1,150 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// extensionCacheVersion is bumped whenever the on-disk extension list format changes.
+// Lists written with a different version are discarded.
+const extensionCacheVersion = 1
+
+// extensionCacheTTL is how long a cached extension list is used, so versions newly published
+// to the extension sources are picked up even when no extension changed locally.
+const extensionCacheTTL = time.Hour
+
+// extensionCacheFile is the extension list as last reported by "azd ext list".
+type extensionCacheFile struct {
+	Version int `json:"version"`
+	// Config identifies the state of the azd config, which records the installed extensions
+	// and the extension sources, when the list was cached.
+	Config     string                 `json:"config"`
+	UpdatedAt  time.Time              `json:"updatedAt"`
+	Extensions []mcpExtensionMetadata `json:"extensions"`
+}
+
+// listExtensions returns the azd extensions tagged for MCP. The list is read from the extension
+// cache while the azd config is unchanged and the cache is recent, so "azd ext list" only runs
+// after an extension or extension source was added, removed, installed or upgraded.
+func listExtensions(ctx context.Context) ([]mcpExtensionMetadata, error) {
+	cachePath, err := extensionCachePath()
+	if err != nil {
+		return nil, err
+	}
+	config := azdConfigState()
+
+	if content, err := os.ReadFile(cachePath); err == nil {
+		var cached extensionCacheFile
+		if err := json.Unmarshal(content, &cached); err == nil && config != "" &&
+			cached.Version == extensionCacheVersion &&
+			cached.Config == config &&
+			time.Since(cached.UpdatedAt) < extensionCacheTTL {
+			return cached.Extensions, nil
+		}
+	}
+
+	extCmd := exec.CommandContext(ctx, "azd", "ext", "list", "--tags", "azure,mcp", "--output", "json")
+	extOut, err := extCmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get extension metadata: %w\n%s", err, string(extOut))
+	}
+	var extList []mcpExtensionMetadata
+	if err := json.Unmarshal(extOut, &extList); err != nil {
+		return nil, fmt.Errorf("failed to parse extension metadata: %w", err)
+	}
+
+	// A cache that cannot be written only costs listing the extensions again on the next start.
+	_ = writeFileAtomic(cachePath, extensionCacheFile{
+		Version:    extensionCacheVersion,
+		Config:     config,
+		UpdatedAt:  time.Now().UTC(),
+		Extensions: extList,
+	})
+
+	return extList, nil
+}
+
+// InstalledExtensions returns the installed version of every azd extension tagged for MCP by
+// extension ID, empty for extensions that are available but not installed. It reads the extension
+// cache, so it is cheap to call until an extension is installed, upgraded or removed.
+func InstalledExtensions(ctx context.Context) (map[string]string, error) {
+	extList, err := listExtensions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]string, len(extList))
+	for _, ext := range extList {
+		if ext.ID == "mcp.azure" {
+			continue
+		}
+		versions[ext.ID] = ""
+		if ext.Installed {
+			versions[ext.ID] = ext.Version
+		}
+	}
+
+	return versions, nil
+}
+
+// azdConfigState returns the size and modification time of the azd config, which change
+// whenever azd installs, upgrades or removes an extension or an extension source.
+func azdConfigState() string {
+	configDir, err := azdConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	info, err := os.Stat(filepath.Join(configDir, "config.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "none"
+	}
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano())
+}
+
+func extensionCachePath() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "extension-list.json"), nil
+}
+
+// writeFileAtomic writes v as JSON through a temporary file so concurrent servers never read a partial file.
+func writeFileAtomic(path string, v any) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -4,23 +4,46 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
-
-	"mcp.azure/resources"
 )
 
 // ExternalToolMetadata implements ToolMetadata for mcp.json tools.
 type ExternalToolMetadata struct {
 	Tool mcpJsonTool
+	// Registry is the mcp.json the tool was registered in.
+	Registry string
+	// Scope is the scope of the registry, such as RegistryUser.
+	Scope string
+
+	// servers decides whether a stdio server may be started.
+	servers installPolicy
 }
 
+// Supported values for the "transport" property of a server in mcp.json.
+const (
+	TransportStreamableHTTP = "streamable-http"
+	TransportSSE            = "sse"
+	TransportStdio          = "stdio"
+)
+
 // mcpJsonTool holds external tool metadata fields.
 type mcpJsonTool struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
-	URL         string `json:"url"`
+	// Transport defaults to streamable-http.
+	Transport string `json:"transport,omitempty"`
+	// URL of the server, for streamable-http and sse.
+	URL string `json:"url,omitempty"`
+	// Command, arguments and additional environment of the server, for stdio.
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	// Auth is optional and describes how to authenticate to the server.
+	Auth *mcpJsonAuth `json:"auth,omitempty"`
 }
 
 type mcpJson struct {
@@ -31,17 +54,14 @@ func (j *ExternalToolMetadata) Metadata() mcp.Tool {
 	return mcp.NewTool(j.Tool.Name, mcp.WithDescription(j.Tool.Description))
 }
 
+func (j *ExternalToolMetadata) Source() string {
+	return j.Registry
+}
+
 func (j *ExternalToolMetadata) CreateClient(ctx context.Context) (*client.Client, error) {
-	endpoint := j.Tool.URL
-	if endpoint == "" {
-		return nil, fmt.Errorf("missing 'url' property for tool %s in mcp.json", j.Tool.Name)
-	}
-	streamingClient, err := client.NewStreamableHttpClient(endpoint)
+	mcpClient, err := j.newClient(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start streaming MCP client for %s: %w", j.Tool.Name, err)
-	}
-	if err := streamingClient.Start(ctx); err != nil {
-		return nil, fmt.Errorf("failed to start streaming MCP client for %s: %w", j.Tool.Name, err)
+		return nil, err
 	}
 
 	initRequest := mcp.InitializeRequest{}
@@ -51,26 +71,181 @@ func (j *ExternalToolMetadata) CreateClient(ctx context.Context) (*client.Client
 		Version: "1.0.0",
 	}
 
-	if _, err := streamingClient.Initialize(ctx, initRequest); err != nil {
-		return nil, fmt.Errorf("failed to initialize streaming MCP client for %s: %w", j.Tool.Name, err)
+	_, err = mcpClient.Initialize(ctx, initRequest)
+	if client.IsOAuthAuthorizationRequiredError(err) {
+		// No stored token yet, or it can no longer be refreshed. Sign in and try again.
+		if authErr := j.Tool.Auth.authorize(ctx, j.Tool.Name, err); authErr != nil {
+			_ = mcpClient.Close()
+			return nil, authErr
+		}
+		_, err = mcpClient.Initialize(ctx, initRequest)
+	}
+	if err != nil {
+		_ = mcpClient.Close()
+		return nil, fmt.Errorf("failed to initialize %s MCP client for %s: %w", j.transport(), j.Tool.Name, err)
+	}
+	return mcpClient, nil
+}
+
+// URL returns the URL of the server, or an empty URL for servers started over stdio.
+func (j *ExternalToolMetadata) URL() string {
+	if j.transport() == TransportStdio {
+		return ""
+	}
+	return j.Tool.URL
+}
+
+// CheckCredentials reports whether the credentials of the server can be acquired without
+// signing in. The description names the credentials, and is empty when none are needed.
+func (j *ExternalToolMetadata) CheckCredentials(ctx context.Context) (string, error) {
+	if err := j.checkAuthScope(); err != nil {
+		return "", err
+	}
+	return j.Tool.Auth.check(ctx, j.Tool.Name, j.Tool.URL)
+}
+
+// checkAuthScope refuses to send the credentials of the user, an Azure token or the value of
+// an environment variable, to a server registered in a project registry. Only the registries
+// the user controls may register servers that get them.
+func (j *ExternalToolMetadata) checkAuthScope() error {
+	if j.Tool.Auth == nil || j.Scope != RegistryProject {
+		return nil
+	}
+
+	switch j.Tool.Auth.Type {
+	case AuthTypeHeader, AuthTypeAzure:
+		return fmt.Errorf(
+			"'auth' type '%s' of tool %s is not allowed in the project registry %s, register the server in the user registry to send it credentials",
+			j.Tool.Auth.Type, j.Tool.Name, j.Registry,
+		)
+	default:
+		return nil
+	}
+}
+
+// commandLine returns the command and arguments a stdio server is started with.
+func (j *ExternalToolMetadata) commandLine() string {
+	return strings.Join(append([]string{j.Tool.Command}, j.Tool.Args...), " ")
+}
+
+// commandDigest identifies the command, arguments and environment a stdio server is started with.
+func (j *ExternalToolMetadata) commandDigest() string {
+	data, _ := json.Marshal(struct {
+		Command string            `json:"command"`
+		Args    []string          `json:"args"`
+		Env     map[string]string `json:"env"`
+	}{j.Tool.Command, j.Tool.Args, j.Tool.Env})
+	return digest(data)
+}
+
+func (j *ExternalToolMetadata) transport() string {
+	if j.Tool.Transport == "" {
+		return TransportStreamableHTTP
+	}
+	return j.Tool.Transport
+}
+
+// newClient creates and starts a client for the configured transport.
+func (j *ExternalToolMetadata) newClient(ctx context.Context) (*client.Client, error) {
+	if err := j.checkAuthScope(); err != nil {
+		return nil, err
+	}
+
+	switch j.transport() {
+	case TransportStreamableHTTP:
+		if j.Tool.URL == "" {
+			return nil, fmt.Errorf("missing 'url' property for tool %s in mcp.json", j.Tool.Name)
+		}
+		auth, err := j.Tool.Auth.resolve(ctx, j.Tool.Name, j.Tool.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		streamingTransport, err := transport.NewStreamableHTTP(j.Tool.URL, auth.streamableHTTPOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start streaming MCP client for %s: %w", j.Tool.Name, err)
+		}
+		streamingClient := newCancellingClient(streamingTransport)
+		if err := streamingClient.Start(ctx); err != nil {
+			return nil, fmt.Errorf("failed to start streaming MCP client for %s: %w", j.Tool.Name, err)
+		}
+		return streamingClient, nil
+
+	case TransportSSE:
+		if j.Tool.URL == "" {
+			return nil, fmt.Errorf("missing 'url' property for tool %s in mcp.json", j.Tool.Name)
+		}
+		auth, err := j.Tool.Auth.resolve(ctx, j.Tool.Name, j.Tool.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		sseTransport, err := transport.NewSSE(j.Tool.URL, auth.sseOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start SSE MCP client for %s: %w", j.Tool.Name, err)
+		}
+		sseClient := newCancellingClient(sseTransport)
+
+		err = sseClient.Start(ctx)
+		if client.IsOAuthAuthorizationRequiredError(err) {
+			// The SSE stream itself requires a token, so sign in before the stream can be opened.
+			if authErr := j.Tool.Auth.authorize(ctx, j.Tool.Name, err); authErr != nil {
+				return nil, authErr
+			}
+			err = sseClient.Start(ctx)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to start SSE MCP client for %s: %w", j.Tool.Name, err)
+		}
+		return sseClient, nil
+
+	case TransportStdio:
+		if j.Tool.Command == "" {
+			return nil, fmt.Errorf("missing 'command' property for tool %s in mcp.json", j.Tool.Name)
+		}
+		if j.Tool.Auth != nil {
+			return nil, fmt.Errorf("'auth' is not supported for stdio tool %s in mcp.json, use 'env' instead", j.Tool.Name)
+		}
+		if err := j.approveStart(ctx); err != nil {
+			return nil, err
+		}
+
+		env := make([]string, 0, len(j.Tool.Env))
+		for key, value := range j.Tool.Env {
+			env = append(env, key+"="+value)
+		}
+
+		stdioClient, err := newStdioClient(ctx, j.Tool.Command, env, j.Tool.Args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start Stdio MCP client for %s: %w", j.Tool.Name, err)
+		}
+		return stdioClient, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported transport '%s' for tool %s in mcp.json", j.Tool.Transport, j.Tool.Name)
 	}
-	return streamingClient, nil
 }
 
 // Loads external (mcp.json) tools as ToolMetadata.
+// Tools are returned in increasing order of registry precedence, see Merge.
 func LoadExternalToolMetadata(ctx context.Context) ([]ToolMetadata, error) {
-	data := resources.McpJson
-	if len(data) == 0 {
-		return nil, nil // not an error if missing
+	registries, err := loadRegistries()
+	if err != nil {
+		return nil, err
 	}
-	var meta mcpJson
-	if err := json.Unmarshal(data, &meta); err != nil {
-		return nil, fmt.Errorf("failed to parse mcp.json: %w", err)
+
+	policy, err := LoadExtensionPolicy()
+	if err != nil {
+		return nil, err
 	}
 
 	var result []ToolMetadata
-	for _, jt := range meta.Servers {
-		result = append(result, &ExternalToolMetadata{Tool: jt})
+	for _, r := range registries {
+		tools, err := r.parse(policy.serverPolicy())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, tools...)
 	}
 	return result, nil
 }
@@ -0,0 +1,194 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrInstallNotApproved is returned when an extension is not installed, because the install
+// policy does not allow it or because the user did not agree to install it.
+var ErrInstallNotApproved = errors.New("extension install not approved")
+
+// ErrNotInstalled is returned when an extension is not installed and may not be installed,
+// see WithoutChanges.
+var ErrNotInstalled = errors.New("extension not installed")
+
+// ErrServerNotApproved is returned when a stdio server of a registry is not started, because
+// the server policy does not allow it or because the user did not agree to start it.
+var ErrServerNotApproved = errors.New("server start not approved")
+
+// installLogFileName is the name of the install decision log in the azd config directory.
+const installLogFileName = "installs.jsonl"
+
+// Install decisions, as recorded in the install log.
+const (
+	InstallApproved    = "approved"
+	InstallDenied      = "denied"
+	InstallDeclined    = "declined"
+	InstallUnconfirmed = "unconfirmed"
+)
+
+// InstallDecision records whether an extension was allowed to be installed and why.
+type InstallDecision struct {
+	Time     time.Time `json:"time"`
+	ID       string    `json:"id"`
+	Source   string    `json:"source,omitempty"`
+	Version  string    `json:"version,omitempty"`
+	Decision string    `json:"decision"`
+	Reason   string    `json:"reason"`
+}
+
+// installLogMu serializes writes to the install log within the process.
+var installLogMu sync.Mutex
+
+// approveInstall decides whether the extension may be installed: it has to be allowed by
+// the install policy and, unless the policy says otherwise, the user has to agree.
+// Every decision is appended to the install log.
+func (a *AzdToolMetadata) approveInstall(ctx context.Context, ext mcpExtensionMetadata, version string) error {
+	decision := InstallDecision{
+		Time:    time.Now().UTC(),
+		ID:      ext.ID,
+		Source:  ext.Source,
+		Version: version,
+	}
+
+	if reason := a.install.check(ext); reason != "" {
+		return recordInstall(decision, InstallDenied, reason)
+	}
+	if a.install.Consent == ConsentNone {
+		return recordInstall(decision, InstallApproved, "allowed by the install policy")
+	}
+
+	if version == "" {
+		version = "latest"
+	}
+	ok, err := confirm(ctx, fmt.Sprintf(
+		"Install the azd extension %s (version %s, source '%s')? It runs on this machine with your Azure credentials.",
+		ext.ID, version, ext.Source,
+	))
+	switch {
+	case errors.Is(err, ErrNoConfirm):
+		return recordInstall(decision, InstallUnconfirmed, "the client cannot ask the user to agree")
+	case err != nil:
+		return recordInstall(decision, InstallUnconfirmed, err.Error())
+	case !ok:
+		return recordInstall(decision, InstallDeclined, "the user declined")
+	}
+
+	return recordInstall(decision, InstallApproved, "the user agreed")
+}
+
+// recordInstall appends the decision to the install log and returns an error unless the install was approved.
+// A log that cannot be written is reported but does not change the decision.
+func recordInstall(decision InstallDecision, outcome string, reason string) error {
+	decision.Decision = outcome
+	decision.Reason = reason
+	if err := appendInstallLog(decision); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record install decision for %s: %v\n", decision.ID, err)
+	}
+
+	if outcome == InstallApproved {
+		return nil
+	}
+	return fmt.Errorf(
+		"%w: %s: %s. Install it with 'azd ext install %s', or allow it in the extension policy",
+		ErrInstallNotApproved, decision.ID, reason, decision.ID,
+	)
+}
+
+// approveStart decides whether the stdio server may be started: it has to be allowed by the
+// server policy and, unless the policy says otherwise, the user has to agree once to its command.
+// The server is asked about again whenever its command, arguments or environment change.
+// Every decision is appended to the install log.
+func (j *ExternalToolMetadata) approveStart(ctx context.Context) error {
+	name := j.Tool.Name
+	command := j.commandDigest()
+	reason := j.servers.checkServer(name, j.Scope)
+
+	switch {
+	case reason == "" && (j.servers.Consent == ConsentNone || isApprovedServer(name, command)):
+		return nil
+	case withoutChanges(ctx) && reason != "":
+		return fmt.Errorf("%w: %s: %s", ErrServerNotApproved, name, reason)
+	case withoutChanges(ctx):
+		return fmt.Errorf("%w: %s: the user is asked to agree on its first call", ErrServerNotApproved, name)
+	}
+
+	decision := InstallDecision{
+		Time:   time.Now().UTC(),
+		ID:     name,
+		Source: j.Registry,
+	}
+	if reason != "" {
+		return recordStart(decision, InstallDenied, reason)
+	}
+
+	ok, err := confirm(ctx, fmt.Sprintf(
+		"Start the MCP server %s registered in %s with the command '%s'? It runs on this machine with your credentials.",
+		name, j.Registry, j.commandLine(),
+	))
+	switch {
+	case errors.Is(err, ErrNoConfirm):
+		return recordStart(decision, InstallUnconfirmed, "the client cannot ask the user to agree")
+	case err != nil:
+		return recordStart(decision, InstallUnconfirmed, err.Error())
+	case !ok:
+		return recordStart(decision, InstallDeclined, "the user declined")
+	}
+
+	if err := approveServer(name, command); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record the approval of server %s: %v\n", name, err)
+	}
+	return recordStart(decision, InstallApproved, "the user agreed")
+}
+
+// recordStart appends the decision to the install log and returns an error unless the start was approved.
+func recordStart(decision InstallDecision, outcome string, reason string) error {
+	decision.Decision = outcome
+	decision.Reason = reason
+	if err := appendInstallLog(decision); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record start decision for %s: %v\n", decision.ID, err)
+	}
+
+	if outcome == InstallApproved {
+		return nil
+	}
+	return fmt.Errorf(
+		"%w: %s: %s. Approve it with 'azd mcp azure server trust --server %s', or allow it in the server policy",
+		ErrServerNotApproved, decision.ID, reason, decision.ID,
+	)
+}
+
+func appendInstallLog(decision InstallDecision) error {
+	configDir, err := azdConfigDir()
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(decision)
+	if err != nil {
+		return err
+	}
+
+	installLogMu.Lock()
+	defer installLogMu.Unlock()
+
+	logPath := filepath.Join(configDir, "mcp.azure", installLogFileName)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
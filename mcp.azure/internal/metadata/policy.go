@@ -0,0 +1,267 @@
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// PolicyEnvVar holds the path of an extension policy file to use instead of the one in the azd config directory.
+const PolicyEnvVar = "AZD_MCP_EXTENSION_POLICY"
+
+// policyFileName is the name of the extension policy file in the azd config directory.
+const policyFileName = "extensions.json"
+
+// Upgrade modes of the extension policy.
+const (
+	// UpgradeAlways upgrades to the newest version allowed by the version constraint.
+	UpgradeAlways = "always"
+	// UpgradePatchOnly only upgrades to newer patch releases of the installed minor version.
+	UpgradePatchOnly = "patch-only"
+	// UpgradeNever keeps the installed version as long as it satisfies the version constraint.
+	UpgradeNever = "never"
+	// UpgradePrompt asks the user before upgrading.
+	UpgradePrompt = "prompt"
+)
+
+// Consent modes of the install policy.
+const (
+	// ConsentPrompt asks the user before an extension is installed for the first time.
+	ConsentPrompt = "prompt"
+	// ConsentNone installs allowed extensions without asking, for unattended use.
+	ConsentNone = "none"
+)
+
+// ExtensionPolicy pins azd extensions to version constraints, controls when they are
+// upgraded and which extensions may be installed.
+//
+//	{
+//	  "upgrade": "patch-only",
+//	  "extensions": {
+//	    "mcp.storage": { "version": "~0.2", "upgrade": "never" }
+//	  },
+//	  "install": {
+//	    "allow": { "sources": ["azd"] },
+//	    "deny": { "ids": ["mcp.experimental.*"] }
+//	  },
+//	  "servers": {
+//	    "deny": { "sources": ["project"] }
+//	  }
+//	}
+type ExtensionPolicy struct {
+	// Upgrade is the upgrade mode of extensions without their own, "always" by default.
+	Upgrade string `json:"upgrade,omitempty"`
+	// Extensions holds the policies of individual extensions by extension ID.
+	Extensions map[string]extensionPin `json:"extensions,omitempty"`
+	// Install controls which extensions may be installed and whether the user is asked first.
+	Install installPolicy `json:"install"`
+	// Servers controls which stdio servers of mcp.json registries may be started and whether
+	// the user is asked first. IDs match server names and sources match registry scopes.
+	Servers installPolicy `json:"servers"`
+}
+
+// extensionPin is the policy of a single extension.
+type extensionPin struct {
+	// Version is a semver constraint, such as "1.2.3", "~1.2" or ">= 1.0, < 2.0".
+	Version string `json:"version,omitempty"`
+	// Upgrade overrides the default upgrade mode.
+	Upgrade string `json:"upgrade,omitempty"`
+
+	constraint *semver.Constraints
+}
+
+// installPolicy decides whether an extension that is not installed yet may be installed.
+type installPolicy struct {
+	// Allow, when not empty, only allows extensions that match it.
+	Allow extensionMatch `json:"allow"`
+	// Deny rejects extensions that match it, even when they are allowed.
+	Deny extensionMatch `json:"deny"`
+	// Consent is "prompt" by default.
+	Consent string `json:"consent,omitempty"`
+}
+
+// extensionMatch matches extensions by ID and by the extension source they come from.
+// Patterns may use the wildcards of path.Match, such as "mcp.*".
+type extensionMatch struct {
+	IDs     []string `json:"ids,omitempty"`
+	Sources []string `json:"sources,omitempty"`
+}
+
+// LoadExtensionPolicy loads the extension policy from AZD_MCP_EXTENSION_POLICY, or from
+// the azd config directory. Without a policy file every extension is installed once the
+// user agrees, and upgraded as soon as a newer version is available.
+func LoadExtensionPolicy() (*ExtensionPolicy, error) {
+	policyPath := os.Getenv(PolicyEnvVar)
+	if policyPath == "" {
+		configDir, err := azdConfigDir()
+		if err != nil {
+			return &ExtensionPolicy{}, nil
+		}
+
+		policyPath = filepath.Join(configDir, "mcp.azure", policyFileName)
+		if _, err := os.Stat(policyPath); errors.Is(err, os.ErrNotExist) {
+			return &ExtensionPolicy{}, nil
+		}
+	}
+
+	data, err := os.ReadFile(policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extension policy %s: %w", policyPath, err)
+	}
+
+	policy := &ExtensionPolicy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse extension policy %s: %w", policyPath, err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid extension policy %s: %w", policyPath, err)
+	}
+
+	return policy, nil
+}
+
+func (p *ExtensionPolicy) validate() error {
+	if err := p.Install.validate("install"); err != nil {
+		return err
+	}
+	if err := p.Servers.validate("server"); err != nil {
+		return err
+	}
+
+	if !validUpgradeMode(p.Upgrade) {
+		return fmt.Errorf("unsupported upgrade mode '%s', expected one of: always, patch-only, never, prompt", p.Upgrade)
+	}
+
+	for id, pin := range p.Extensions {
+		if !validUpgradeMode(pin.Upgrade) {
+			return fmt.Errorf("unsupported upgrade mode '%s' for extension %s, expected one of: always, patch-only, never, prompt", pin.Upgrade, id)
+		}
+		if pin.Version == "" {
+			continue
+		}
+
+		constraint, err := semver.NewConstraint(pin.Version)
+		if err != nil {
+			return fmt.Errorf("invalid version constraint '%s' for extension %s: %w", pin.Version, id, err)
+		}
+		pin.constraint = constraint
+		p.Extensions[id] = pin
+	}
+
+	return nil
+}
+
+// pin returns the policy of the extension with its effective upgrade mode.
+func (p *ExtensionPolicy) pin(id string) extensionPin {
+	var pin extensionPin
+	if p != nil {
+		pin = p.Extensions[id]
+	}
+
+	if pin.Upgrade == "" && p != nil {
+		pin.Upgrade = p.Upgrade
+	}
+	if pin.Upgrade == "" {
+		pin.Upgrade = UpgradeAlways
+	}
+
+	return pin
+}
+
+// installPolicy returns the install policy, the default policy allows every extension after consent.
+func (p *ExtensionPolicy) installPolicy() installPolicy {
+	var install installPolicy
+	if p != nil {
+		install = p.Install
+	}
+	if install.Consent == "" {
+		install.Consent = ConsentPrompt
+	}
+	return install
+}
+
+// serverPolicy returns the server policy, the default policy allows every stdio server after consent.
+func (p *ExtensionPolicy) serverPolicy() installPolicy {
+	var servers installPolicy
+	if p != nil {
+		servers = p.Servers
+	}
+	if servers.Consent == "" {
+		servers.Consent = ConsentPrompt
+	}
+	return servers
+}
+
+func (p installPolicy) validate(kind string) error {
+	if p.Consent != "" && p.Consent != ConsentPrompt && p.Consent != ConsentNone {
+		return fmt.Errorf("unsupported %s consent '%s', expected one of: prompt, none", kind, p.Consent)
+	}
+	for _, pattern := range slices.Concat(p.Allow.IDs, p.Allow.Sources, p.Deny.IDs, p.Deny.Sources) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid %s pattern '%s': %w", kind, pattern, err)
+		}
+	}
+	return nil
+}
+
+// check returns why the extension may not be installed, or an empty string if it may.
+func (p installPolicy) check(ext mcpExtensionMetadata) string {
+	switch {
+	case matchesAny(p.Deny.IDs, ext.ID):
+		return fmt.Sprintf("extension %s is denied by the install policy", ext.ID)
+	case matchesAny(p.Deny.Sources, ext.Source):
+		return fmt.Sprintf("extension source '%s' is denied by the install policy", ext.Source)
+	case len(p.Allow.IDs) > 0 && !matchesAny(p.Allow.IDs, ext.ID):
+		return fmt.Sprintf("extension %s is not allowed by the install policy", ext.ID)
+	case len(p.Allow.Sources) > 0 && !matchesAny(p.Allow.Sources, ext.Source):
+		return fmt.Sprintf("extension source '%s' is not allowed by the install policy", ext.Source)
+	default:
+		return ""
+	}
+}
+
+// checkServer returns why the stdio server registered in a registry of the scope may not be
+// started, or an empty string if it may.
+func (p installPolicy) checkServer(name string, scope string) string {
+	switch {
+	case matchesAny(p.Deny.IDs, name):
+		return fmt.Sprintf("server %s is denied by the server policy", name)
+	case matchesAny(p.Deny.Sources, scope):
+		return fmt.Sprintf("servers of %s registries are denied by the server policy", scope)
+	case len(p.Allow.IDs) > 0 && !matchesAny(p.Allow.IDs, name):
+		return fmt.Sprintf("server %s is not allowed by the server policy", name)
+	case len(p.Allow.Sources) > 0 && !matchesAny(p.Allow.Sources, scope):
+		return fmt.Sprintf("servers of %s registries are not allowed by the server policy", scope)
+	default:
+		return ""
+	}
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether the version satisfies the version constraint, if any.
+func (p extensionPin) allows(v *semver.Version) bool {
+	return p.constraint == nil || p.constraint.Check(v)
+}
+
+func validUpgradeMode(mode string) bool {
+	switch mode {
+	case "", UpgradeAlways, UpgradePatchOnly, UpgradeNever, UpgradePrompt:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,179 @@
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"mcp.azure/resources"
+)
+
+// RegistryEnvVar holds one or more additional mcp.json registry paths, separated by
+// the OS path list separator. Registries from the environment have the highest precedence.
+const RegistryEnvVar = "AZD_MCP_REGISTRY"
+
+// registryFileName is the name of the user and project level registry files.
+const registryFileName = "mcp.json"
+
+// toolNamePattern matches the names servers may be registered with. Names are used in file
+// names, such as for OAuth tokens, so they cannot contain path separators.
+var toolNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Scopes of the registries, in increasing order of precedence.
+const (
+	// RegistryEmbedded is the mcp.json embedded in the root server.
+	RegistryEmbedded = "embedded"
+	// RegistryUser is the mcp.json in the azd config directory.
+	RegistryUser = "user"
+	// RegistryProject is the mcp.json next to azure.yaml, only loaded once the user trusted it.
+	RegistryProject = "project"
+	// RegistryEnv is a registry listed in AZD_MCP_REGISTRY.
+	RegistryEnv = "env"
+)
+
+// registry is a single mcp.json document and where it was loaded from.
+type registry struct {
+	source string
+	scope  string
+	data   []byte
+}
+
+// Duplicate describes a tool name that was registered more than once.
+// The registration with the higher precedence is kept and the other is ignored.
+type Duplicate struct {
+	Name    string
+	Kept    string
+	Ignored string
+}
+
+func (d Duplicate) String() string {
+	return fmt.Sprintf("tool '%s' from %s is ignored because it is also registered by %s", d.Name, d.Ignored, d.Kept)
+}
+
+// Merge combines groups of tool metadata into a single list with unique names.
+// Later groups take precedence over earlier ones, and within a group the last
+// registration wins. Every name collision is returned so it can be reported.
+// Registries are merged before the azd extensions, so no registry can take the
+// place of an installed extension.
+func Merge(groups ...[]ToolMetadata) ([]ToolMetadata, []Duplicate) {
+	var result []ToolMetadata
+	var duplicates []Duplicate
+	index := make(map[string]int)
+
+	for _, group := range groups {
+		for _, tm := range group {
+			name := tm.Metadata().Name
+			i, exists := index[name]
+			if !exists {
+				index[name] = len(result)
+				result = append(result, tm)
+				continue
+			}
+
+			duplicates = append(duplicates, Duplicate{
+				Name:    name,
+				Kept:    tm.Source(),
+				Ignored: result[i].Source(),
+			})
+			result[i] = tm
+		}
+	}
+
+	return result, duplicates
+}
+
+// loadRegistries returns every external server registry in increasing order of precedence:
+// the embedded defaults, the user registry, the project registry next to azure.yaml when
+// the user trusted it, and finally any registries listed in AZD_MCP_REGISTRY.
+func loadRegistries() ([]registry, error) {
+	registries := []registry{
+		{source: "embedded mcp.json", scope: RegistryEmbedded, data: resources.McpJson},
+	}
+
+	if configDir, err := azdConfigDir(); err == nil {
+		path := filepath.Join(configDir, "mcp.azure", registryFileName)
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("failed to read registry %s: %w", path, err)
+		default:
+			registries = append(registries, registry{source: path, scope: RegistryUser, data: data})
+		}
+	}
+
+	// Any repository can have a project registry, it is only loaded once the user trusted
+	// its content, see TrustProjectRegistry.
+	if path, ok := ProjectRegistry(); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read registry %s: %w", path, err)
+		}
+		if isTrusted(path, data) {
+			registries = append(registries, registry{source: path, scope: RegistryProject, data: data})
+		}
+	}
+
+	// Registries named explicitly in the environment must exist.
+	for _, path := range filepath.SplitList(os.Getenv(RegistryEnvVar)) {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read registry %s from %s: %w", path, RegistryEnvVar, err)
+		}
+		registries = append(registries, registry{source: path, scope: RegistryEnv, data: data})
+	}
+
+	return registries, nil
+}
+
+// parse returns the external tools declared in the registry, whose stdio servers are started
+// as the server policy allows.
+func (r registry) parse(servers installPolicy) ([]ToolMetadata, error) {
+	if len(r.data) == 0 {
+		return nil, nil
+	}
+
+	var meta mcpJson
+	if err := json.Unmarshal(r.data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", r.source, err)
+	}
+
+	var result []ToolMetadata
+	for _, jt := range meta.Servers {
+		if jt.Name == "" {
+			return nil, fmt.Errorf("missing 'name' property for server in %s", r.source)
+		}
+		if !toolNamePattern.MatchString(jt.Name) {
+			return nil, fmt.Errorf("invalid 'name' property '%s' for server in %s, expected letters, digits, '.', '-' and '_'", jt.Name, r.source)
+		}
+		result = append(result, &ExternalToolMetadata{Tool: jt, Registry: r.source, Scope: r.scope, servers: servers})
+	}
+
+	return result, nil
+}
+
+// findProjectDir walks up from the working directory looking for azure.yaml.
+func findProjectDir() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "azure.yaml")); err == nil {
+			return dir, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,95 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Notification methods that mcp-go has no constants for.
+const (
+	MethodNotificationCancelled = "notifications/cancelled"
+	MethodNotificationProgress  = "notifications/progress"
+)
+
+// cancelNotificationTimeout bounds how long sending notifications/cancelled to a child may take.
+const cancelNotificationTimeout = 5 * time.Second
+
+// cancellingTransport tells the child server when a request is abandoned because its
+// context was cancelled, so the child can stop the work instead of finishing it unseen.
+// mcp-go only stops waiting for the response.
+type cancellingTransport struct {
+	transport.Interface
+}
+
+// newCancellingClient returns a client whose abandoned requests are cancelled on the child.
+// The client must be started, which also delivers child notifications to its handlers.
+func newCancellingClient(t transport.Interface) *client.Client {
+	return client.NewClient(&cancellingTransport{Interface: t})
+}
+
+func (t *cancellingTransport) SendRequest(ctx context.Context, request transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
+	response, err := t.Interface.SendRequest(ctx, request)
+	if err != nil && ctx.Err() != nil && request.Method != string(mcp.MethodInitialize) {
+		// The request context is done, so the notification needs its own.
+		notifyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelNotificationTimeout)
+		defer cancel()
+
+		_ = t.Interface.SendNotification(notifyCtx, mcp.JSONRPCNotification{
+			JSONRPC: mcp.JSONRPC_VERSION,
+			Notification: mcp.Notification{
+				Method: MethodNotificationCancelled,
+				Params: mcp.NotificationParams{
+					AdditionalFields: map[string]any{
+						"requestId": request.ID,
+						"reason":    context.Cause(ctx).Error(),
+					},
+				},
+			},
+		})
+	}
+	return response, err
+}
+
+// SetRequestHandler forwards server to client requests, such as sampling, when the transport supports them.
+func (t *cancellingTransport) SetRequestHandler(handler transport.RequestHandler) {
+	if bidirectional, ok := t.Interface.(transport.BidirectionalInterface); ok {
+		bidirectional.SetRequestHandler(handler)
+	}
+}
+
+// SetProtocolVersion forwards the negotiated protocol version to HTTP transports.
+func (t *cancellingTransport) SetProtocolVersion(version string) {
+	if httpConn, ok := t.Interface.(transport.HTTPConnection); ok {
+		httpConn.SetProtocolVersion(version)
+	}
+}
+
+// Stderr returns the stderr of a stdio child server.
+// The reader is closed when the child process exits.
+func Stderr(c *client.Client) (io.Reader, bool) {
+	t := c.GetTransport()
+	if wrapped, ok := t.(*cancellingTransport); ok {
+		t = wrapped.Interface
+	}
+
+	stdio, ok := t.(*transport.Stdio)
+	if !ok {
+		return nil, false
+	}
+	return stdio.Stderr(), true
+}
+
+// newStdioClient spawns a stdio child server and returns a started client for it.
+func newStdioClient(ctx context.Context, command string, env []string, args ...string) (*client.Client, error) {
+	mcpClient := newCancellingClient(transport.NewStdio(command, env, args...))
+	if err := mcpClient.Start(ctx); err != nil {
+		return nil, fmt.Errorf("failed to start stdio transport: %w", err)
+	}
+	return mcpClient, nil
+}
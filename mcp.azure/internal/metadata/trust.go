@@ -0,0 +1,226 @@
+package metadata
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// trustFileName is the name of the file in the config directory that records the trusted
+// project registries and the stdio servers the user agreed to start.
+const trustFileName = "trust.json"
+
+// trustFile maps the path of every trusted project registry to the digest of its trusted
+// content, and the name of every approved stdio server to the digest of its command.
+type trustFile struct {
+	Registries map[string]string `json:"registries"`
+	Servers    map[string]string `json:"servers,omitempty"`
+}
+
+// ProjectRegistry returns the path of the mcp.json next to the azure.yaml of the project
+// the working directory is in, if the project has one.
+func ProjectRegistry() (string, bool) {
+	projectDir, ok := findProjectDir()
+	if !ok {
+		return "", false
+	}
+
+	path := filepath.Join(projectDir, registryFileName)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// UntrustedRegistry is a project registry that is not loaded because the user has not
+// trusted it, or its content changed since.
+type UntrustedRegistry struct {
+	Path string
+}
+
+func (u UntrustedRegistry) String() string {
+	return fmt.Sprintf("project registry %s is not trusted and was not loaded, run 'azd mcp azure server trust' in the project to load it", u.Path)
+}
+
+// UntrustedProjectRegistry returns the project registry when it is not loaded because it is not trusted.
+func UntrustedProjectRegistry() (UntrustedRegistry, bool) {
+	path, ok := ProjectRegistry()
+	if !ok {
+		return UntrustedRegistry{}, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return UntrustedRegistry{}, false
+	}
+	return UntrustedRegistry{Path: path}, !isTrusted(path, data)
+}
+
+// TrustProjectRegistry trusts the current content of the project registry, which is loaded
+// from then on until its content changes. It returns the path of the registry.
+func TrustProjectRegistry() (string, error) {
+	path, ok := ProjectRegistry()
+	if !ok {
+		return "", errors.New("no mcp.json found next to the azure.yaml of the project")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read registry %s: %w", path, err)
+	}
+
+	trust, err := loadTrust()
+	if err != nil {
+		return "", err
+	}
+	trust.Registries[path] = digest(data)
+
+	return path, saveTrust(trust)
+}
+
+// DistrustProjectRegistry stops loading the project registry. It returns the path of the registry.
+func DistrustProjectRegistry() (string, error) {
+	path, ok := ProjectRegistry()
+	if !ok {
+		return "", errors.New("no mcp.json found next to the azure.yaml of the project")
+	}
+
+	trust, err := loadTrust()
+	if err != nil {
+		return "", err
+	}
+	delete(trust.Registries, path)
+
+	return path, saveTrust(trust)
+}
+
+// isTrusted reports whether the user trusted the registry at path with exactly this content.
+func isTrusted(path string, data []byte) bool {
+	trust, err := loadTrust()
+	if err != nil {
+		return false
+	}
+
+	return trust.Registries[path] == digest(data)
+}
+
+// TrustServer records that the user agrees to start the stdio server registered under the name
+// with its current command, arguments and environment. It returns the command line.
+func TrustServer(ctx context.Context, name string) (string, error) {
+	server, err := findStdioServer(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	return server.commandLine(), approveServer(name, server.commandDigest())
+}
+
+// DistrustServer forgets that the user agreed to start the stdio server registered under the name.
+func DistrustServer(name string) error {
+	trust, err := loadTrust()
+	if err != nil {
+		return err
+	}
+	delete(trust.Servers, name)
+
+	return saveTrust(trust)
+}
+
+// findStdioServer returns the stdio server registered under the name with the highest precedence.
+func findStdioServer(ctx context.Context, name string) (*ExternalToolMetadata, error) {
+	tools, err := LoadExternalToolMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var server *ExternalToolMetadata
+	for _, tm := range tools {
+		if external, ok := tm.(*ExternalToolMetadata); ok && external.Tool.Name == name {
+			server = external
+		}
+	}
+	if server == nil || server.transport() != TransportStdio {
+		return nil, fmt.Errorf("no stdio server %s is registered in mcp.json", name)
+	}
+
+	return server, nil
+}
+
+// isApprovedServer reports whether the user agreed to start the stdio server with this command.
+func isApprovedServer(name string, command string) bool {
+	trust, err := loadTrust()
+	if err != nil {
+		return false
+	}
+
+	return trust.Servers[name] == command
+}
+
+// approveServer records that the user agreed to start the stdio server with this command.
+func approveServer(name string, command string) error {
+	trust, err := loadTrust()
+	if err != nil {
+		return err
+	}
+	trust.Servers[name] = command
+
+	return saveTrust(trust)
+}
+
+func loadTrust() (*trustFile, error) {
+	trustPath, err := trustFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	trust := &trustFile{}
+	content, err := os.ReadFile(trustPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read trust file: %w", err)
+	default:
+		if err := json.Unmarshal(content, trust); err != nil {
+			return nil, fmt.Errorf("failed to parse trust file %s: %w", trustPath, err)
+		}
+	}
+	if trust.Registries == nil {
+		trust.Registries = make(map[string]string)
+	}
+	if trust.Servers == nil {
+		trust.Servers = make(map[string]string)
+	}
+
+	return trust, nil
+}
+
+func saveTrust(trust *trustFile) error {
+	trustPath, err := trustFilePath()
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(trustPath, trust); err != nil {
+		return fmt.Errorf("failed to write trust file: %w", err)
+	}
+	return nil
+}
+
+func trustFilePath() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, trustFileName), nil
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -10,5 +10,7 @@ import (
 // ToolMetadata provides a unified interface for tool metadata and client creation.
 type ToolMetadata interface {
 	Metadata() mcp.Tool
+	// Source describes where the tool was registered, for diagnostics.
+	Source() string
 	CreateClient(ctx context.Context) (*client.Client, error)
 }
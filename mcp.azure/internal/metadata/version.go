@@ -0,0 +1,150 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// ExtensionVersion describes the version of an azd extension and the policy it was resolved with.
+type ExtensionVersion struct {
+	ID string `json:"id"`
+	// Version is the installed version, empty until the extension is installed.
+	Version       string `json:"version,omitempty"`
+	LatestVersion string `json:"latestVersion,omitempty"`
+	Constraint    string `json:"constraint,omitempty"`
+	Upgrade       string `json:"upgrade"`
+	// UpgradeAvailable is a newer version that the upgrade mode held back.
+	UpgradeAvailable string `json:"upgradeAvailable,omitempty"`
+}
+
+// VersionInfo reports the installed version of the extension and its version policy.
+func (a *AzdToolMetadata) VersionInfo() ExtensionVersion {
+	ext := a.extension()
+	info := ExtensionVersion{
+		ID:            ext.ID,
+		LatestVersion: ext.LatestVersion,
+		Constraint:    a.pin.Version,
+		Upgrade:       a.pin.Upgrade,
+	}
+	if !ext.Installed {
+		return info
+	}
+
+	info.Version = ext.Version
+	installed, installedErr := semver.NewVersion(ext.Version)
+	latest, latestErr := semver.NewVersion(ext.LatestVersion)
+	if installedErr == nil && latestErr == nil && latest.GreaterThan(installed) && a.pin.allows(latest) {
+		info.UpgradeAvailable = ext.LatestVersion
+	}
+
+	return info
+}
+
+// resolveVersion returns the version to install or upgrade the extension to, following its
+// version constraint and upgrade mode. An empty version keeps the installed version, or
+// installs the latest one when the extension is not installed and not pinned.
+func (a *AzdToolMetadata) resolveVersion(ctx context.Context, ext mcpExtensionMetadata) (string, error) {
+	pin := a.pin
+
+	var installed *semver.Version
+	if ext.Installed {
+		installed, _ = semver.NewVersion(ext.Version)
+	}
+	// A missing or unsupported installed version has to be replaced, whatever the upgrade mode.
+	keep := installed != nil && pin.allows(installed)
+	if keep && pin.Upgrade == UpgradeNever {
+		return "", nil
+	}
+
+	eligible := func(v *semver.Version) bool {
+		if !pin.allows(v) {
+			return false
+		}
+		if !keep {
+			return true
+		}
+		if !v.GreaterThan(installed) {
+			return false
+		}
+		if pin.Upgrade == UpgradePatchOnly {
+			return v.Major() == installed.Major() && v.Minor() == installed.Minor()
+		}
+		return true
+	}
+
+	target := newestVersion(eligible, ext.LatestVersion)
+	latest, err := semver.NewVersion(ext.LatestVersion)
+	if target == nil && (!keep || (err == nil && latest.GreaterThan(installed))) {
+		// azd only lists the latest version, older ones are looked up when the latest is not allowed.
+		if versions, err := availableVersions(ctx, ext.ID); err == nil {
+			target = newestVersion(eligible, versions...)
+		}
+	}
+
+	switch {
+	case target == nil && keep:
+		return "", nil
+	case target == nil && pin.constraint == nil:
+		// Nothing to compare against, install the latest version or keep the installed one.
+		return "", nil
+	case target == nil:
+		return "", fmt.Errorf("no available version of extension %s satisfies the version constraint '%s'", ext.ID, pin.Version)
+	}
+
+	if keep && pin.Upgrade == UpgradePrompt {
+		ok, err := confirm(ctx, fmt.Sprintf("Upgrade the azd extension %s from version %s to %s?", ext.ID, ext.Version, target.Original()))
+		if err != nil || !ok {
+			// Without consent the installed version keeps working.
+			return "", nil
+		}
+	}
+
+	return target.Original(), nil
+}
+
+// newestVersion returns the newest of the versions that is eligible, nil if there is none.
+func newestVersion(eligible func(*semver.Version) bool, versions ...string) *semver.Version {
+	var newest *semver.Version
+	for _, version := range versions {
+		v, err := semver.NewVersion(version)
+		if err != nil || !eligible(v) {
+			continue
+		}
+		if newest == nil || v.GreaterThan(newest) {
+			newest = v
+		}
+	}
+	return newest
+}
+
+// isDowngrade reports whether moving from the installed version to the target goes back.
+func isDowngrade(installed string, target string) bool {
+	from, fromErr := semver.NewVersion(installed)
+	to, toErr := semver.NewVersion(target)
+	return fromErr == nil && toErr == nil && to.LessThan(from)
+}
+
+// availableVersions returns every version of the extension published to its source.
+func availableVersions(ctx context.Context, id string) ([]string, error) {
+	showCmd := exec.CommandContext(ctx, "azd", "ext", "show", id, "--output", "json")
+	showOut, err := showCmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get versions of extension %s: %w\n%s", id, err, string(showOut))
+	}
+
+	var show struct {
+		AvailableVersions []string `json:"availableVersions"`
+	}
+	if err := json.Unmarshal(showOut, &show); err != nil {
+		return nil, fmt.Errorf("failed to parse versions of extension %s: %w", id, err)
+	}
+	if len(show.AvailableVersions) == 0 {
+		return nil, fmt.Errorf("no versions listed for extension %s", id)
+	}
+
+	return show.AvailableVersions, nil
+}
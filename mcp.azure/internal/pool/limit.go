@@ -0,0 +1,119 @@
+package pool
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// limit bounds the number of child clients running across the pools that share it.
+// Every entry of a pool holds a slot of its limit from creation until it is removed.
+type limit struct {
+	max int
+
+	mu      sync.Mutex
+	running int
+	pools   map[*Pool]struct{}
+	// released is closed and replaced whenever a client of any of the pools stops being used
+	// or is removed, waking callers that are waiting for room.
+	released chan struct{}
+}
+
+func newLimit(max int) *limit {
+	return &limit{
+		max:      max,
+		pools:    make(map[*Pool]struct{}),
+		released: make(chan struct{}),
+	}
+}
+
+func (l *limit) add(p *Pool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.pools[p] = struct{}{}
+}
+
+func (l *limit) remove(p *Pool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.pools, p)
+}
+
+// reserve takes a slot for a new child, reporting false when the limit is reached.
+func (l *limit) reserve() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.max > 0 && l.running >= l.max {
+		return false
+	}
+	l.running++
+	return true
+}
+
+// free gives back the slot of a child that was removed from its pool.
+func (l *limit) free() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.running--
+	l.notifyLocked()
+}
+
+// wait returns a channel that is closed the next time a client is released or removed.
+func (l *limit) wait() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.released
+}
+
+func (l *limit) notify() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.notifyLocked()
+}
+
+func (l *limit) notifyLocked() {
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// closeIdle closes the least recently used idle child of any of the pools, reporting whether
+// one was closed. It must be called without holding the lock of any pool.
+func (l *limit) closeIdle() bool {
+	l.mu.Lock()
+	pools := slices.Collect(maps.Keys(l.pools))
+	l.mu.Unlock()
+
+	var lruPool *Pool
+	var lruName string
+	var lru *entry
+	var lruUsed time.Time
+	for _, p := range pools {
+		p.mu.Lock()
+		name, e := p.idleLocked()
+		if e != nil && (lru == nil || e.lastUsed.Before(lruUsed)) {
+			lruPool, lruName, lru, lruUsed = p, name, e, e.lastUsed
+		}
+		p.mu.Unlock()
+	}
+
+	if lru == nil {
+		return false
+	}
+
+	lruPool.mu.Lock()
+	defer lruPool.mu.Unlock()
+
+	// The child may have been used or removed since it was found.
+	if lruPool.entries[lruName] != lru || lru.inUse > 0 {
+		return false
+	}
+	lruPool.removeLocked(lruName, lru)
+	return true
+}
@@ -0,0 +1,563 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp.azure/internal/metadata"
+)
+
+var (
+	// ErrClosed is returned when a client is requested from a pool that has been closed.
+	ErrClosed = errors.New("client pool is closed")
+
+	// ErrChildExited is returned when a child server exits or its transport breaks during a call.
+	// The dead client has already been evicted and the next call will respawn it.
+	ErrChildExited = errors.New("child server exited")
+)
+
+// StartError reports that the client for a child tool could not be created.
+type StartError struct {
+	Tool string
+	Err  error
+}
+
+func (e *StartError) Error() string {
+	return fmt.Sprintf("failed to start tool client for %s: %v", e.Tool, e.Err)
+}
+
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
+
+const (
+	// minRestartDelay is the delay before respawning a child after its first crash.
+	minRestartDelay = 500 * time.Millisecond
+	// maxRestartDelay caps the exponential restart backoff.
+	maxRestartDelay = 30 * time.Second
+	// stableAfter is how long a child must stay up before its crash count is reset.
+	stableAfter = time.Minute
+	// closeTimeout bounds how long Close waits for children that are still being created.
+	closeTimeout = 5 * time.Second
+)
+
+// Option configures a Pool.
+type Option func(*Pool)
+
+// WithIdleTimeout closes child clients that have not been used for the given duration.
+// A zero duration keeps children running until the pool is closed.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(p *Pool) {
+		p.idleTimeout = timeout
+	}
+}
+
+// WithMaxClients limits how many child clients can run at once across every pool created with
+// the option, so the option passed to NewSessions bounds the children of all sessions together.
+// When the limit is reached the least recently used idle child of any of the pools is closed
+// to make room. A value of zero means no limit.
+func WithMaxClients(max int) Option {
+	l := newLimit(max)
+	return func(p *Pool) {
+		p.limit = l
+	}
+}
+
+// WithStartHandler calls fn whenever a child client has been started. fn must not block.
+func WithStartHandler(fn func(name string, c *client.Client)) Option {
+	return func(p *Pool) {
+		p.onStart = fn
+	}
+}
+
+// WithNotificationHandler calls fn with the notifications children send, other than
+// progress notifications, which are routed to the call they belong to. fn must not block.
+func WithNotificationHandler(fn func(name string, notification mcp.JSONRPCNotification)) Option {
+	return func(p *Pool) {
+		p.onNotification = fn
+	}
+}
+
+// Pool caches MCP clients for child tools and owns their lifecycle.
+// Clients are created lazily on first use and concurrent requests for the same
+// tool share a single CreateClient call. Children that exit are evicted and
+// respawned with exponential backoff on their next use.
+type Pool struct {
+	idleTimeout    time.Duration
+	limit          *limit
+	onStart        func(name string, c *client.Client)
+	onNotification func(name string, notification mcp.JSONRPCNotification)
+
+	mu       sync.Mutex
+	entries  map[string]*entry
+	restarts map[string]*restartState
+	closed   bool
+	done     chan struct{}
+
+	// progress routes progress notifications of children to the call they belong to, by progress token.
+	progressMu sync.Mutex
+	progress   map[string]func(mcp.ProgressNotificationParams)
+}
+
+// entry tracks a single child client. ready is closed once creation finished,
+// after which client or err is set. dead is closed when the child transport breaks.
+type entry struct {
+	ready     chan struct{}
+	client    *client.Client
+	err       error
+	startedAt time.Time
+
+	// inUse and lastUsed are guarded by Pool.mu.
+	inUse    int
+	lastUsed time.Time
+
+	dead     chan struct{}
+	deadOnce sync.Once
+}
+
+// restartState tracks consecutive crashes of a child so restarts can back off.
+type restartState struct {
+	crashes   int
+	notBefore time.Time
+}
+
+// New creates an empty client pool.
+func New(options ...Option) *Pool {
+	p := &Pool{
+		entries:  make(map[string]*entry),
+		restarts: make(map[string]*restartState),
+		done:     make(chan struct{}),
+		progress: make(map[string]func(mcp.ProgressNotificationParams)),
+	}
+
+	for _, opt := range options {
+		opt(p)
+	}
+	if p.limit == nil {
+		p.limit = newLimit(0)
+	}
+	p.limit.add(p)
+
+	if p.idleTimeout > 0 {
+		go p.reapIdle()
+	}
+
+	return p
+}
+
+// Call runs fn against the tool's client.
+// If the child exits or its transport breaks while fn is running, the client is
+// evicted and an error wrapping ErrChildExited is returned. Whether the call is
+// safe to repeat is left to the caller.
+func (p *Pool) Call(ctx context.Context, tm metadata.ToolMetadata, fn func(context.Context, *client.Client) error) error {
+	e, err := p.acquire(ctx, tm)
+	if err != nil {
+		return err
+	}
+	defer p.release(e)
+
+	// Abort the in-flight request as soon as the child dies, the stdio transport
+	// would otherwise wait forever for a response that never arrives.
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-e.dead:
+			cancel()
+		case <-callCtx.Done():
+		}
+	}()
+
+	err = fn(callCtx, e.client)
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() == nil && (e.isDead() || isTransportError(err)) {
+		name := tm.Metadata().Name
+		p.evict(name, e)
+		return fmt.Errorf("%w: %s: %w", ErrChildExited, name, err)
+	}
+
+	return err
+}
+
+// acquire returns a live entry for the tool, replacing any entry whose child has died.
+// The entry is marked in use and must be handed back with release.
+func (p *Pool) acquire(ctx context.Context, tm metadata.ToolMetadata) (*entry, error) {
+	name := tm.Metadata().Name
+
+	p.mu.Lock()
+	var e *entry
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrClosed
+		}
+
+		existing, ok := p.entries[name]
+		if ok && existing.isReady() && existing.isDead() {
+			p.evictLocked(name, existing)
+			ok = false
+		}
+		if ok {
+			e = existing
+			break
+		}
+
+		released := p.limit.wait()
+		if p.limit.reserve() {
+			e = &entry{
+				ready: make(chan struct{}),
+				dead:  make(chan struct{}),
+			}
+			p.entries[name] = e
+			delay := p.restartDelayLocked(name)
+
+			go p.create(context.WithoutCancel(ctx), name, e, tm, delay)
+			break
+		}
+
+		// The limit is reached, close the least recently used idle child of any session or
+		// else wait until one is released.
+		p.mu.Unlock()
+		if !p.limit.closeIdle() {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-released:
+			}
+		}
+		p.mu.Lock()
+	}
+	e.inUse++
+	p.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		p.release(e)
+		return nil, ctx.Err()
+	case <-e.ready:
+	}
+
+	if e.err != nil {
+		p.release(e)
+		return nil, e.err
+	}
+
+	return e, nil
+}
+
+// release marks the entry as no longer used by the caller.
+func (p *Pool) release(e *entry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.inUse--
+	e.lastUsed = time.Now()
+	p.limit.notify()
+}
+
+// idleLocked returns the least recently used child that is running and not in use, if any.
+func (p *Pool) idleLocked() (string, *entry) {
+	var lruName string
+	var lru *entry
+	for name, e := range p.entries {
+		if e.inUse > 0 || !e.isReady() {
+			continue
+		}
+		if lru == nil || e.lastUsed.Before(lru.lastUsed) {
+			lruName, lru = name, e
+		}
+	}
+
+	return lruName, lru
+}
+
+// create starts the child client and publishes the result to any waiters.
+// Failed entries are removed so the next call can try again.
+// The client is created with the values of the call that first needed it, so the user
+// of its session can be asked for consent, but is not cancelled with that call as a
+// cancelled tool call must not tear down a shared child server. It is cancelled when the
+// pool is closed, so an install or a consent prompt does not hold up Close.
+func (p *Pool) create(ctx context.Context, name string, e *entry, tm metadata.ToolMetadata, delay time.Duration) {
+	defer close(e.ready)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-p.done:
+			e.err = ErrClosed
+			return
+		}
+	}
+
+	mcpClient, err := tm.CreateClient(ctx)
+	if err != nil {
+		e.err = &StartError{Tool: name, Err: err}
+		p.mu.Lock()
+		if p.entries[name] == e {
+			delete(p.entries, name)
+			p.limit.free()
+		}
+		p.mu.Unlock()
+		return
+	}
+	mcpClient.OnNotification(func(notification mcp.JSONRPCNotification) {
+		p.notify(name, notification)
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The pool may have been closed while the client was starting.
+	if p.closed {
+		_ = mcpClient.Close()
+		e.err = ErrClosed
+		return
+	}
+
+	e.client = mcpClient
+	e.startedAt = time.Now()
+	e.lastUsed = e.startedAt
+
+	// A stdio child closes its stderr when the process exits. Draining it also
+	// keeps a chatty child from blocking on a full pipe.
+	if stderr, ok := metadata.Stderr(mcpClient); ok {
+		go func() {
+			_, _ = io.Copy(io.Discard, stderr)
+			e.markDead()
+		}()
+	}
+
+	if p.onStart != nil {
+		p.onStart(name, mcpClient)
+	}
+}
+
+// TrackProgress delivers the progress notifications children send for the token to fn,
+// until the returned stop function is called. Clients keep their progress tokens unique
+// across their requests, and every client session has its own pool.
+func (p *Pool) TrackProgress(token mcp.ProgressToken, fn func(mcp.ProgressNotificationParams)) (stop func()) {
+	key := fmt.Sprint(token)
+
+	p.progressMu.Lock()
+	p.progress[key] = fn
+	p.progressMu.Unlock()
+
+	return func() {
+		p.progressMu.Lock()
+		delete(p.progress, key)
+		p.progressMu.Unlock()
+	}
+}
+
+// notify routes a progress notification from a child to the call tracking it, and any other
+// notification to the notification handler.
+func (p *Pool) notify(name string, notification mcp.JSONRPCNotification) {
+	if notification.Method != metadata.MethodNotificationProgress {
+		if p.onNotification != nil {
+			p.onNotification(name, notification)
+		}
+		return
+	}
+
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return
+	}
+	var progress mcp.ProgressNotification
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return
+	}
+
+	p.progressMu.Lock()
+	fn, ok := p.progress[fmt.Sprint(progress.Params.ProgressToken)]
+	p.progressMu.Unlock()
+
+	if ok {
+		fn(progress.Params)
+	}
+}
+
+// evict removes a dead entry, closes its client and schedules the restart backoff.
+func (p *Pool) evict(name string, e *entry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.evictLocked(name, e)
+}
+
+func (p *Pool) evictLocked(name string, e *entry) {
+	e.markDead()
+	if p.entries[name] != e {
+		return
+	}
+
+	state, ok := p.restarts[name]
+	if !ok || time.Since(e.startedAt) > stableAfter {
+		state = &restartState{}
+		p.restarts[name] = state
+	}
+	state.crashes++
+
+	delay := minRestartDelay << (state.crashes - 1)
+	if delay > maxRestartDelay || delay <= 0 {
+		delay = maxRestartDelay
+	}
+	state.notBefore = time.Now().Add(delay)
+
+	p.removeLocked(name, e)
+}
+
+// removeLocked drops the entry from the pool and closes its client in the background.
+func (p *Pool) removeLocked(name string, e *entry) {
+	delete(p.entries, name)
+	p.limit.free()
+
+	if e.client != nil {
+		go e.client.Close()
+	}
+}
+
+// restartDelayLocked returns how long to wait before respawning a crashed child.
+func (p *Pool) restartDelayLocked(name string) time.Duration {
+	state, ok := p.restarts[name]
+	if !ok {
+		return 0
+	}
+
+	return time.Until(state.notBefore)
+}
+
+// reapIdle periodically closes children that have been idle longer than the idle timeout.
+// They are started again lazily on their next use.
+func (p *Pool) reapIdle() {
+	interval := p.idleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		for name, e := range p.entries {
+			if e.inUse == 0 && e.isReady() && time.Since(e.lastUsed) > p.idleTimeout {
+				p.removeLocked(name, e)
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// Close closes all child clients and prevents new ones from being created. Children that are
+// still being created are cancelled, and closed by create if they start before closeTimeout.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	entries := p.entries
+	p.entries = make(map[string]*entry)
+	for range entries {
+		p.limit.free()
+	}
+	p.limit.notify()
+	p.mu.Unlock()
+	p.limit.remove(p)
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	var errs []error
+	for name, e := range entries {
+		select {
+		case <-e.ready:
+		case <-waitCtx.Done():
+			continue
+		}
+		if e.client == nil {
+			continue
+		}
+		if err := e.client.Close(); err != nil && !e.isDead() {
+			errs = append(errs, fmt.Errorf("failed to close client for %s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e *entry) isReady() bool {
+	select {
+	case <-e.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func (e *entry) isDead() bool {
+	select {
+	case <-e.dead:
+		return true
+	default:
+		return false
+	}
+}
+
+func (e *entry) markDead() {
+	e.deadOnce.Do(func() {
+		close(e.dead)
+	})
+}
+
+// isTransportError reports whether err came from a broken connection to the child
+// rather than from the child rejecting the request.
+func isTransportError(err error) bool {
+	if errors.Is(err, metadata.ErrCredentialsUnavailable) {
+		// The request was never sent, the connection is fine.
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+		return true
+	}
+
+	msg := err.Error()
+	for _, s := range []string{"transport error", "broken pipe", "file already closed", "connection refused", "connection reset"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
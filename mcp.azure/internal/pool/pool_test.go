@@ -0,0 +1,295 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// fakeTransport is the transport of a fake child, which only records that it was closed.
+type fakeTransport struct {
+	closed *atomic.Int32
+}
+
+func (t *fakeTransport) Start(ctx context.Context) error { return nil }
+
+func (t *fakeTransport) SendRequest(ctx context.Context, request transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (t *fakeTransport) SendNotification(ctx context.Context, notification mcp.JSONRPCNotification) error {
+	return nil
+}
+
+func (t *fakeTransport) SetNotificationHandler(handler func(notification mcp.JSONRPCNotification)) {}
+
+func (t *fakeTransport) Close() error {
+	t.closed.Add(1)
+	return nil
+}
+
+func (t *fakeTransport) GetSessionId() string { return "" }
+
+// fakeTool is a child tool whose clients are fake, counting how many were created and closed.
+type fakeTool struct {
+	name string
+	// create runs before every client is created, when set.
+	create func(ctx context.Context) error
+
+	created atomic.Int32
+	closed  atomic.Int32
+}
+
+func (f *fakeTool) Metadata() mcp.Tool { return mcp.Tool{Name: f.name} }
+
+func (f *fakeTool) Source() string { return "test" }
+
+func (f *fakeTool) CreateClient(ctx context.Context) (*client.Client, error) {
+	if f.create != nil {
+		if err := f.create(ctx); err != nil {
+			return nil, err
+		}
+	}
+	f.created.Add(1)
+	return client.NewClient(&fakeTransport{closed: &f.closed}), nil
+}
+
+func call(p *Pool, tm *fakeTool) error {
+	return p.Call(context.Background(), tm, func(context.Context, *client.Client) error { return nil })
+}
+
+// eventually waits for cond to hold, failing the test after a few seconds.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCallCreatesClientOnce(t *testing.T) {
+	tm := &fakeTool{name: "storage", create: func(ctx context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}}
+	p := New()
+	defer p.Close()
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := call(p, tm); err != nil {
+				t.Errorf("Call() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := tm.created.Load(); got != 1 {
+		t.Errorf("created %d clients, want 1", got)
+	}
+}
+
+func TestCallReturnsStartError(t *testing.T) {
+	startErr := errors.New("install failed")
+	tm := &fakeTool{name: "storage", create: func(ctx context.Context) error { return startErr }}
+	p := New()
+	defer p.Close()
+
+	err := call(p, tm)
+	var se *StartError
+	if !errors.As(err, &se) || se.Tool != "storage" || !errors.Is(err, startErr) {
+		t.Fatalf("Call() error = %v, want a StartError for storage wrapping %v", err, startErr)
+	}
+
+	// A failed start is not cached, the next call tries again.
+	tm.create = nil
+	if err := call(p, tm); err != nil {
+		t.Fatalf("Call() after failed start error = %v", err)
+	}
+}
+
+func TestCloseClosesEveryClient(t *testing.T) {
+	tools := []*fakeTool{{name: "storage"}, {name: "keyvault"}, {name: "cosmos"}}
+	p := New()
+
+	for _, tm := range tools {
+		if err := call(p, tm); err != nil {
+			t.Fatalf("Call(%s) error = %v", tm.name, err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	for _, tm := range tools {
+		if got := tm.closed.Load(); got != 1 {
+			t.Errorf("client of %s closed %d times, want 1", tm.name, got)
+		}
+	}
+	if err := call(p, tools[0]); !errors.Is(err, ErrClosed) {
+		t.Errorf("Call() after Close error = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestCloseCancelsClientCreation(t *testing.T) {
+	started := make(chan struct{})
+	tm := &fakeTool{name: "storage", create: func(ctx context.Context) error {
+		// Like an install or a consent prompt that waits for the user.
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	p := New()
+
+	callErr := make(chan error, 1)
+	go func() {
+		callErr <- call(p, tm)
+	}()
+	<-started
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- p.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	case <-time.After(closeTimeout / 2):
+		t.Fatal("Close() waited for the client being created")
+	}
+	if err := <-callErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("Call() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCrashedClientIsEvictedAndRestartedWithBackoff(t *testing.T) {
+	tm := &fakeTool{name: "storage"}
+	p := New()
+	defer p.Close()
+
+	err := p.Call(context.Background(), tm, func(context.Context, *client.Client) error {
+		return io.EOF
+	})
+	if !errors.Is(err, ErrChildExited) {
+		t.Fatalf("Call() error = %v, want %v", err, ErrChildExited)
+	}
+	eventually(t, "the crashed client to be closed", func() bool { return tm.closed.Load() == 1 })
+
+	start := time.Now()
+	if err := call(p, tm); err != nil {
+		t.Fatalf("Call() after crash error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < minRestartDelay*8/10 {
+		t.Errorf("restarted after %v, want a backoff of %v", elapsed, minRestartDelay)
+	}
+	if got := tm.created.Load(); got != 2 {
+		t.Errorf("created %d clients, want 2", got)
+	}
+}
+
+func TestRejectedRequestKeepsClient(t *testing.T) {
+	tm := &fakeTool{name: "storage"}
+	p := New()
+	defer p.Close()
+
+	rejected := errors.New("invalid argument")
+	err := p.Call(context.Background(), tm, func(context.Context, *client.Client) error {
+		return rejected
+	})
+	if !errors.Is(err, rejected) || errors.Is(err, ErrChildExited) {
+		t.Fatalf("Call() error = %v, want %v", err, rejected)
+	}
+	if err := call(p, tm); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if got := tm.created.Load(); got != 1 {
+		t.Errorf("created %d clients, want 1", got)
+	}
+}
+
+func TestIdleClientsAreReaped(t *testing.T) {
+	tm := &fakeTool{name: "storage"}
+	p := New(WithIdleTimeout(100 * time.Millisecond))
+	defer p.Close()
+
+	if err := call(p, tm); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	eventually(t, "the idle client to be closed", func() bool { return tm.closed.Load() == 1 })
+
+	// The child is started again on its next use.
+	if err := call(p, tm); err != nil {
+		t.Fatalf("Call() after reaping error = %v", err)
+	}
+	if got := tm.created.Load(); got != 2 {
+		t.Errorf("created %d clients, want 2", got)
+	}
+}
+
+func TestMaxClientsAcrossSessions(t *testing.T) {
+	sessions := NewSessions(WithMaxClients(1))
+	defer sessions.Close()
+
+	first, err := sessions.Get("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := sessions.Get("second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := &fakeTool{name: "storage"}
+	keyvault := &fakeTool{name: "keyvault"}
+
+	// The idle child of the first session is closed to make room for the second session.
+	if err := call(first, storage); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if err := call(second, keyvault); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	eventually(t, "the idle client of the first session to be closed", func() bool { return storage.closed.Load() == 1 })
+
+	// A child in use is never closed, the other session waits for room.
+	release := make(chan struct{})
+	inUse := make(chan struct{})
+	go func() {
+		_ = second.Call(context.Background(), keyvault, func(context.Context, *client.Client) error {
+			close(inUse)
+			<-release
+			return nil
+		})
+	}()
+	<-inUse
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = first.Call(ctx, storage, func(context.Context, *client.Client) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Call() at the limit error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	if err := call(first, storage); err != nil {
+		t.Fatalf("Call() once released error = %v", err)
+	}
+	eventually(t, "the released client of the second session to be closed", func() bool { return keyvault.closed.Load() == 1 })
+}
@@ -0,0 +1,117 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Sessions keeps a separate Pool for every MCP client session, so child servers
+// and any state they hold are never shared between clients of a hosted root server.
+// Options apply to each session's pool individually, except the limit of WithMaxClients
+// which is shared by the pools of all sessions.
+type Sessions struct {
+	options        []Option
+	onStart        func(sessionID string, name string, c *client.Client)
+	onNotification func(sessionID string, name string, notification mcp.JSONRPCNotification)
+
+	mu     sync.Mutex
+	pools  map[string]*Pool
+	closed bool
+}
+
+// NewSessions creates an empty set of session pools.
+func NewSessions(options ...Option) *Sessions {
+	return &Sessions{
+		options: options,
+		pools:   make(map[string]*Pool),
+	}
+}
+
+// Get returns the pool for the session, creating it on first use.
+func (s *Sessions) Get(sessionID string) (*Pool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+
+	p, ok := s.pools[sessionID]
+	if !ok {
+		p = New(s.sessionOptions(sessionID)...)
+		s.pools[sessionID] = p
+	}
+
+	return p, nil
+}
+
+// OnStart calls fn whenever a child client of any session has been started. fn must not block.
+func (s *Sessions) OnStart(fn func(sessionID string, name string, c *client.Client)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onStart = fn
+}
+
+// OnNotification calls fn with the notifications children of any session send, other than
+// progress notifications. fn must not block.
+func (s *Sessions) OnNotification(fn func(sessionID string, name string, notification mcp.JSONRPCNotification)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onNotification = fn
+}
+
+// sessionOptions returns the options of a new pool, with the handlers bound to its session.
+func (s *Sessions) sessionOptions(sessionID string) []Option {
+	options := slices.Clone(s.options)
+	if onStart := s.onStart; onStart != nil {
+		options = append(options, WithStartHandler(func(name string, c *client.Client) {
+			onStart(sessionID, name, c)
+		}))
+	}
+	if onNotification := s.onNotification; onNotification != nil {
+		options = append(options, WithNotificationHandler(func(name string, notification mcp.JSONRPCNotification) {
+			onNotification(sessionID, name, notification)
+		}))
+	}
+
+	return options
+}
+
+// Remove closes the pool of a session that has ended.
+func (s *Sessions) Remove(sessionID string) error {
+	s.mu.Lock()
+	p, ok := s.pools[sessionID]
+	delete(s.pools, sessionID)
+	s.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return p.Close()
+}
+
+// Close closes the pools of all sessions and prevents new ones from being created.
+func (s *Sessions) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	pools := s.pools
+	s.pools = make(map[string]*Pool)
+	s.mu.Unlock()
+
+	var errs []error
+	for sessionID, p := range pools {
+		if err := p.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close clients for session %s: %w", sessionID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp.azure/internal/audit"
+	"mcp.azure/internal/metadata"
+)
+
+// maxAuditErrorLength bounds the error text recorded for a failed command.
+const maxAuditErrorLength = 1024
+
+// WithAuditLog records every command dispatched to a child tool in the audit log.
+func WithAuditLog(log *audit.Log) Option {
+	return func(a *AzureTool) {
+		a.audit = log
+	}
+}
+
+// record appends a dispatched command and its outcome to the audit log.
+// A log that cannot be written is reported but does not fail the command.
+func (a *AzureTool) record(
+	ctx context.Context,
+	tm metadata.ToolMetadata,
+	intent string,
+	request mcp.CallToolRequest,
+	result *mcp.CallToolResult,
+	duration time.Duration,
+) {
+	if a.audit == nil {
+		return
+	}
+
+	entry := audit.Entry{
+		Time:       time.Now().UTC().Add(-duration),
+		Session:    sessionID(ctx),
+		Intent:     intent,
+		Tool:       tm.Metadata().Name,
+		Command:    request.Params.Name,
+		Parameters: request.Params.Arguments,
+		DurationMs: duration.Milliseconds(),
+		Outcome:    audit.OutcomeSuccess,
+	}
+	if azdTool, ok := tm.(*metadata.AzdToolMetadata); ok {
+		entry.Version = azdTool.VersionInfo().Version
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		entry.Outcome = audit.OutcomeCancelled
+	case isDryRun(result):
+		entry.Outcome = audit.OutcomeDryRun
+	case result.IsError:
+		entry.Outcome = audit.OutcomeError
+		entry.ErrorCode, entry.Error = resultError(result)
+	}
+
+	if err := a.audit.Record(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record %s %s in the audit log: %v\n", entry.Tool, entry.Command, err)
+	}
+}
+
+// resultError returns the error code and message of an error result. Errors returned
+// by the child as plain text have no code.
+func resultError(result *mcp.CallToolResult) (string, string) {
+	if e, ok := result.StructuredContent.(toolError); ok {
+		return e.Code, e.Message
+	}
+
+	for _, content := range result.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			if len(text.Text) > maxAuditErrorLength {
+				return "", text.Text[:maxAuditErrorLength]
+			}
+			return "", text.Text
+		}
+	}
+
+	return "", ""
+}
+
+func isDryRun(result *mcp.CallToolResult) bool {
+	_, ok := result.StructuredContent.(dispatchPlan)
+	return ok
+}
@@ -0,0 +1,525 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp.azure/internal/audit"
+	"mcp.azure/internal/metadata"
+	"mcp.azure/internal/pool"
+)
+
+const azureToolDescription = `
+	This server/tool provides real-time, programmatic access to all Azure products, services, and resources,
+	as well as all interactions with the Azure Developer CLI (azd).
+	Use this tool for any Azure control plane or data plane operation, including resource management and automation.
+	To discover available capabilities, call the tool with the "learn" parameter to get a list of top-level tools.
+	To explore further, set "learn" and specify a tool name to retrieve supported commands and their parameters.
+	To execute an action, set the "tool", "command", and convert the users intent into the "parameters" based on the discovered schema.
+	Always use this tool for any Azure or "azd" related operation requiring up-to-date, dynamic, and interactive capabilities.
+`
+
+// AzureTool is the single root "azure" tool that learns about and dispatches to child tools.
+type AzureTool struct {
+	childTools      []mcp.Tool
+	toolMetadataMap map[string]metadata.ToolMetadata
+	clients         *pool.Sessions
+	schemas         *metadata.SchemaCache
+	duplicates      []metadata.Duplicate
+	audit           *audit.Log
+	readOnly        bool
+	dryRun          bool
+	confirmations   *confirmTokens
+	contexts        *sessionContexts
+
+	// onCommands is called whenever the commands of a child tool are listed.
+	onCommands func(tm metadata.ToolMetadata, commands []mcp.Tool)
+
+	// toolIndex is the search index over the names and descriptions of the child tools.
+	toolIndex *searchIndex
+
+	// listed holds the commands listed since the server started, for tools whose
+	// schemas are not cached on disk.
+	listedMu sync.Mutex
+	listed   map[string][]mcp.Tool
+	// indexes holds the search index over the known commands of every child tool, see commandIndex.
+	indexes map[string]*searchIndex
+}
+
+// Option configures an AzureTool.
+type Option func(*AzureTool)
+
+// WithSchemaCache reads child commands from the cache when possible so children
+// do not have to be started just to be learned about.
+func WithSchemaCache(schemas *metadata.SchemaCache) Option {
+	return func(a *AzureTool) {
+		a.schemas = schemas
+	}
+}
+
+// WithDuplicates reports tool registrations that were ignored in favor of another
+// registration with the same name in the root "learn" output.
+func WithDuplicates(duplicates []metadata.Duplicate) Option {
+	return func(a *AzureTool) {
+		a.duplicates = duplicates
+	}
+}
+
+// NewAzureTool creates the root tool over the given child tool metadata.
+// Tool names are expected to be unique, see metadata.Merge.
+// Child clients are resolved through the pool of the calling session.
+func NewAzureTool(allTools []metadata.ToolMetadata, clients *pool.Sessions, options ...Option) *AzureTool {
+	// Build []mcp.Tool for learn output and a map for fast lookup
+	var childTools []mcp.Tool
+	toolMetadataMap := make(map[string]metadata.ToolMetadata)
+	toolIndex := newSearchIndex()
+	for _, t := range allTools {
+		meta := t.Metadata()
+		childTools = append(childTools, meta)
+		toolMetadataMap[meta.Name] = t
+		toolIndex.addTool(meta)
+	}
+
+	a := &AzureTool{
+		childTools:      childTools,
+		toolMetadataMap: toolMetadataMap,
+		clients:         clients,
+		toolIndex:       toolIndex,
+		listed:          make(map[string][]mcp.Tool),
+		indexes:         make(map[string]*searchIndex),
+		confirmations:   newConfirmTokens(),
+		contexts:        newSessionContexts(),
+	}
+
+	for _, opt := range options {
+		opt(a)
+	}
+
+	return a
+}
+
+// Tool returns the MCP definition of the root "azure" tool.
+func (a *AzureTool) Tool() mcp.Tool {
+	return mcp.NewTool(
+		"azure",
+		mcp.WithDescription(azureToolDescription+a.modesDescription()),
+		mcp.WithString("intent",
+			mcp.Required(),
+			mcp.Description("The intent of the operation the user wants to perform against azure. When \"command\" is omitted the intent is used to pick the tool and command."),
+		),
+		mcp.WithString("tool",
+			mcp.Description("The azure tool to use to execute the operation."),
+		),
+		mcp.WithString("command",
+			mcp.Description("The command to execute against the specified tool. Without a tool, \"set-context\" stores \"subscription\" and \"tenant\" as the defaults of the session, an empty value clears a default."),
+		),
+		mcp.WithString("subscription",
+			mcp.Description("The subscription ID or name to run the command in. Passed to the command when it takes a subscription parameter, the call fails when it does not. Defaults to the subscription set with \"set-context\"."),
+		),
+		mcp.WithString("tenant",
+			mcp.Description("The tenant ID to run the command in. Passed to the command when it takes a tenant parameter, the call fails when it does not. Defaults to the tenant set with \"set-context\"."),
+		),
+		mcp.WithObject("parameters",
+			mcp.Description("The parameters to pass to the tool"),
+		),
+		mcp.WithString("query",
+			mcp.Description("Search the known commands of all tools, or of the given tool, by name, description and parameter names. Returns the best matches with their parameters."),
+		),
+		mcp.WithBoolean("learn",
+			mcp.Description("To learn about the tool and its supported child tools and parameters."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("diagnose",
+			mcp.Description("Check azd, az, the extensions, credentials and every tool server, and return a report of what works and what has to be fixed."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("Validate the command and return what would be run without running it. Destructive commands also return a \"confirm\" token."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithArray("plan",
+			mcp.Description("Run several commands in order, as a list of steps with \"tool\", \"command\" and \"parameters\". Parameters can reference the output of an earlier step, such as \"${steps.0.id}\". Returns the combined result of all steps."),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"tool":       map[string]any{"type": "string"},
+					"command":    map[string]any{"type": "string"},
+					"parameters": map[string]any{"type": "object"},
+					"confirm":    map[string]any{"type": "string", "description": "The confirm token returned for this step by a dry run of the plan."},
+				},
+				"required": []string{"tool", "command"},
+			}),
+		),
+		mcp.WithBoolean("stopOnError",
+			mcp.Description("Stop the plan at the first step that fails."),
+			mcp.DefaultBool(true),
+		),
+		mcp.WithString("confirm",
+			mcp.Description("The confirm token returned by a dry run of the same destructive command, once the user agreed to run it. Only needed when the client cannot ask the user to confirm."),
+		),
+	)
+}
+
+// Handle is the server.ToolHandlerFunc for the root "azure" tool.
+func (a *AzureTool) Handle(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	toolName, hasToolName := request.GetArguments()["tool"].(string)
+
+	commandName, hasCommandName := request.GetArguments()["command"].(string)
+	query, _ := request.GetArguments()["query"].(string)
+	if commandName == "" && strings.TrimSpace(query) != "" {
+		return a.searchCommands(query, toolName)
+	}
+
+	if diagnose, _ := request.GetArguments()["diagnose"].(bool); diagnose {
+		return a.diagnose(ctx)
+	}
+
+	learn, ok := request.GetArguments()["learn"].(bool)
+	if ok && learn {
+		if hasToolName && toolName != "" {
+			return a.learnTool(ctx, toolName)
+		}
+
+		return a.learnRoot()
+	}
+
+	if plan, ok := request.GetArguments()["plan"].([]any); ok && len(plan) > 0 {
+		return a.runPlan(ctx, request, plan)
+	}
+
+	if commandName == setContextCommand && (toolName == "" || toolName == "azure") {
+		return a.setContext(ctx, request)
+	}
+
+	intent, _ := request.GetArguments()["intent"].(string)
+	if commandName == "" && strings.TrimSpace(intent) != "" {
+		return a.routeIntent(ctx, request, intent, toolName)
+	}
+
+	if !hasToolName || !hasCommandName {
+		return errorResult(toolError{
+			Code:       codeInvalidParameters,
+			Tool:       toolName,
+			Command:    commandName,
+			Message:    `the "tool" and "command" parameters are required when not learning`,
+			NextAction: `Run again with the "learn" argument to get a list of available tools and their parameters.`,
+		}, `
+			The "tool" and "command" parameters are required when not learning
+			Run again with the "learn" argument to get a list of available tools and their parameters.
+			To learn about a specific tool, use the "tool" argument with the name of the tool.
+		`), nil
+	}
+	tm, ok := a.toolMetadataMap[toolName]
+	if !ok {
+		return toolNotFoundResult(toolName), nil
+	}
+
+	params := request.GetArguments()["parameters"]
+
+	return a.dispatch(ctx, tm, newDispatchOptions(request), commandRequest(request, commandName, params)), nil
+}
+
+// learnRoot returns the list of top-level tools.
+func (a *AzureTool) learnRoot() (*mcp.CallToolResult, error) {
+	toolsJson, err := json.MarshalIndent(a.childTools, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed get get learn content: %w", err)
+	}
+
+	result := mcp.NewToolResultText(string(toolsJson))
+	if len(a.duplicates) > 0 {
+		var notes strings.Builder
+		notes.WriteString("Some tools were registered more than once:\n")
+		for _, d := range a.duplicates {
+			notes.WriteString("- " + d.String() + "\n")
+		}
+		result.Content = append(result.Content, mcp.NewTextContent(notes.String()))
+	}
+
+	var versions []metadata.ExtensionVersion
+	for _, t := range a.childTools {
+		if azdTool, ok := a.toolMetadataMap[t.Name].(*metadata.AzdToolMetadata); ok {
+			versions = append(versions, azdTool.VersionInfo())
+		}
+	}
+	if len(versions) > 0 {
+		versionsJson, err := json.MarshalIndent(versions, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed get get learn content: %w", err)
+		}
+		result.Content = append(result.Content, mcp.NewTextContent("Extension versions:\n"+string(versionsJson)))
+	}
+
+	return result, nil
+}
+
+// learnTool returns the commands and parameters supported by a child tool.
+func (a *AzureTool) learnTool(ctx context.Context, toolName string) (*mcp.CallToolResult, error) {
+	tm, ok := a.toolMetadataMap[toolName]
+	if !ok {
+		return toolNotFoundResult(toolName), nil
+	}
+
+	childTools, err := a.listCommands(ctx, tm)
+	if err != nil {
+		return callErrorResult(toolName, "", err), nil
+	}
+	toolsJson, err := json.MarshalIndent(mcp.ListToolsResult{Tools: childTools}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed get get learn content: %w", err)
+	}
+	learnContent := fmt.Sprintf(`
+		Here are the available command and their parameters for '%s' tool.
+		If you do not find a suitable tool, run again with the "learn" argument and empty "tool" to get a list of available tools and their parameters.
+
+		%s
+	`, toolName, string(toolsJson))
+
+	result := mcp.NewToolResultText(learnContent)
+	if azdTool, ok := tm.(*metadata.AzdToolMetadata); ok {
+		versionJson, err := json.MarshalIndent(azdTool.VersionInfo(), "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed get get learn content: %w", err)
+		}
+		result.Content = append(result.Content, mcp.NewTextContent("Extension version:\n"+string(versionJson)))
+	}
+
+	return result, nil
+}
+
+// listCommands returns the commands exposed by a child tool, preferring the schema cache
+// so the child does not have to be started just to be learned about.
+// Listing is side-effect free, so it is retried once if the child died mid-call.
+func (a *AzureTool) listCommands(ctx context.Context, tm metadata.ToolMetadata) ([]mcp.Tool, error) {
+	if commands, ok := a.schemas.Tools(tm); ok {
+		a.commandsListed(tm, commands)
+		return commands, nil
+	}
+
+	var result *mcp.ListToolsResult
+	list := func(ctx context.Context, c *client.Client) error {
+		var err error
+		result, err = c.ListTools(ctx, mcp.ListToolsRequest{})
+		return err
+	}
+
+	err := a.call(ctx, tm, list)
+	if errors.Is(err, pool.ErrChildExited) {
+		err = a.call(ctx, tm, list)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A cache that cannot be written only costs a child start on the next learn.
+	_ = a.schemas.SetTools(tm, result.Tools)
+	a.commandsListed(tm, result.Tools)
+
+	return result.Tools, nil
+}
+
+func (a *AzureTool) commandsListed(tm metadata.ToolMetadata, commands []mcp.Tool) {
+	a.listedMu.Lock()
+	a.listed[tm.Metadata().Name] = commands
+	delete(a.indexes, tm.Metadata().Name)
+	a.listedMu.Unlock()
+
+	if a.onCommands != nil {
+		a.onCommands(tm, commands)
+	}
+}
+
+// dispatchOptions are the arguments of the root tool call that apply to the dispatched command.
+type dispatchOptions struct {
+	intent  string
+	dryRun  bool
+	confirm string
+	// context is the subscription and tenant given for the call.
+	context callContext
+	// references reports that the parameters of a plan step reference the output of earlier
+	// steps. They are resolved before the step runs, but left as they are in a dry run, so no
+	// confirm token can be issued for the step in advance.
+	references bool
+	// flat reports that the command was called as a flat tool rather than through the azure tool.
+	flat bool
+}
+
+func newDispatchOptions(request mcp.CallToolRequest) dispatchOptions {
+	intent, _ := request.GetArguments()["intent"].(string)
+	dryRun, _ := request.GetArguments()["dryRun"].(bool)
+	confirm, _ := request.GetArguments()["confirm"].(string)
+	subscription, _ := request.GetArguments()["subscription"].(string)
+	tenant, _ := request.GetArguments()["tenant"].(string)
+
+	return dispatchOptions{
+		intent:  intent,
+		dryRun:  dryRun,
+		confirm: confirm,
+		context: callContext{
+			Subscription: strings.TrimSpace(subscription),
+			Tenant:       strings.TrimSpace(tenant),
+		},
+	}
+}
+
+// dispatch runs a command of a child tool and records it in the audit log.
+func (a *AzureTool) dispatch(ctx context.Context, tm metadata.ToolMetadata, opts dispatchOptions, request mcp.CallToolRequest) *mcp.CallToolResult {
+	start := time.Now()
+	result, request := a.execute(ctx, tm, opts, request)
+	a.record(ctx, tm, opts.intent, request, result, time.Since(start))
+
+	return result
+}
+
+// execute validates the parameters against the command's input schema before calling it,
+// so agents get a precise list of problems instead of an opaque error from the child.
+// Write commands are refused in read-only mode, nothing is called in a dry run, and
+// destructive commands have to be confirmed by the user.
+// The subscription and tenant of the call are injected into the parameters first, and the
+// request is returned with the parameters the command was run with.
+func (a *AzureTool) execute(ctx context.Context, tm metadata.ToolMetadata, opts dispatchOptions, request mcp.CallToolRequest) (*mcp.CallToolResult, mcp.CallToolRequest) {
+	toolName := tm.Metadata().Name
+	commandName := request.Params.Name
+
+	commands, err := a.listCommands(ctx, tm)
+	if err != nil {
+		return callErrorResult(toolName, commandName, err), request
+	}
+	command, ok := findCommand(commands, commandName)
+	if !ok {
+		return commandNotFoundResult(toolName, commandName, commands), request
+	}
+
+	params, notes, unsupported := a.withContext(ctx, opts, command, request.Params.Arguments)
+	if len(unsupported) > 0 {
+		return contextUnsupportedResult(toolName, commandName, unsupported), request
+	}
+	request.Params.Arguments = params
+
+	return withNotes(a.run(ctx, tm, opts, command, request), notes), request
+}
+
+// run checks the mode and confirmation of a command with validated parameters and calls it.
+func (a *AzureTool) run(ctx context.Context, tm metadata.ToolMetadata, opts dispatchOptions, command mcp.Tool, request mcp.CallToolRequest) *mcp.CallToolResult {
+	toolName := tm.Metadata().Name
+	commandName := request.Params.Name
+
+	readOnly, classification := classifyCommand(command)
+	if a.readOnly && !readOnly {
+		return readOnlyResult(toolName, commandName, classification)
+	}
+
+	schema := inputSchema(command)
+	// References to earlier plan steps are only left unresolved in a dry run.
+	unresolved := opts.references && (a.dryRun || opts.dryRun)
+	if problems := validateParameters(schema, request.Params.Arguments, unresolved); len(problems) > 0 {
+		return invalidParametersResult(toolName, commandName, schema, problems)
+	}
+
+	destructive := isDestructive(command)
+	if a.dryRun || opts.dryRun {
+		var token string
+		if destructive && !a.dryRun && !opts.references {
+			var err error
+			if token, err = a.confirmations.issue(sessionID(ctx), toolName, request); err != nil {
+				return callErrorResult(toolName, commandName, err)
+			}
+		}
+		result := dryRunResult(tm, request, readOnly, classification, destructive, token)
+		if destructive && !a.dryRun && opts.references {
+			result = withNotes(result, []string{"The step is destructive and its parameters reference earlier steps, so it has to be confirmed once they are resolved when the plan runs."})
+		}
+		return result
+	}
+
+	if destructive {
+		if result := a.confirmDestructive(ctx, tm, opts, request); result != nil {
+			return result
+		}
+	}
+
+	result, err := a.callCommand(ctx, tm, request)
+	if err != nil {
+		return callErrorResult(toolName, commandName, err)
+	}
+	return result
+}
+
+// callCommand dispatches a command to a child tool.
+// If the child died mid-call the command is retried once against a respawned child,
+// but only when the command is known to be idempotent.
+func (a *AzureTool) callCommand(
+	ctx context.Context,
+	tm metadata.ToolMetadata,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	ctx = metadata.WithConfirm(ctx, elicitConfirm)
+	clients, err := a.sessionPool(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if progress := newProgressRelay(ctx, request); progress != nil {
+		defer clients.TrackProgress(progress.token, progress.relay)()
+		defer progress.heartbeat(tm.Metadata().Name, request.Params.Name)()
+	}
+
+	var result *mcp.CallToolResult
+	callTool := func(ctx context.Context, c *client.Client) error {
+		var err error
+		result, err = c.CallTool(ctx, request)
+		return err
+	}
+
+	err = clients.Call(ctx, tm, callTool)
+	if !errors.Is(err, pool.ErrChildExited) {
+		return result, err
+	}
+
+	commands, listErr := a.listCommands(ctx, tm)
+	if listErr != nil {
+		return nil, err
+	}
+	command, ok := findCommand(commands, request.Params.Name)
+	if !ok || !isIdempotent(command) {
+		return nil, err
+	}
+
+	err = clients.Call(ctx, tm, callTool)
+	return result, err
+}
+
+// call runs fn against the child tool's client from the calling session's pool.
+// Starting the child may ask the user for confirmation, such as to upgrade an extension.
+func (a *AzureTool) call(ctx context.Context, tm metadata.ToolMetadata, fn func(context.Context, *client.Client) error) error {
+	ctx = metadata.WithConfirm(ctx, elicitConfirm)
+	clients, err := a.sessionPool(ctx)
+	if err != nil {
+		return err
+	}
+
+	return clients.Call(ctx, tm, fn)
+}
+
+// sessionPool returns the pool of child clients of the calling session.
+func (a *AzureTool) sessionPool(ctx context.Context) (*pool.Pool, error) {
+	return a.clients.Get(sessionID(ctx))
+}
+
+// sessionID returns the ID of the calling session, or an empty ID outside of a session.
+func sessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+
+	return ""
+}
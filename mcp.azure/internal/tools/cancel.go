@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// errRequestCancelled is the cause of a tool call context cancelled by the client.
+var errRequestCancelled = errors.New("request cancelled by the client")
+
+// Cancellations cancels the context of a running tool call when the client sends
+// notifications/cancelled for it, which mcp-go does not do by itself. A cancelled
+// context is in turn propagated to the child server handling the call.
+type Cancellations struct {
+	mu    sync.Mutex
+	calls map[string]context.CancelCauseFunc
+	// pending holds the ids of the tools/call requests between the BeforeCallTool hook and the
+	// tool handler, keyed by the context of the request, which mcp-go gives both of them.
+	pending map[context.Context]string
+}
+
+// NewCancellations creates an empty set of cancellable calls.
+func NewCancellations() *Cancellations {
+	return &Cancellations{
+		calls:   make(map[string]context.CancelCauseFunc),
+		pending: make(map[context.Context]string),
+	}
+}
+
+// BeforeCallTool is a server.OnBeforeCallToolFunc hook that records the id of the request,
+// which the tool handler is not given otherwise.
+func (c *Cancellations) BeforeCallTool(ctx context.Context, id any, request *mcp.CallToolRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending[ctx] = mcp.NewRequestId(id).String()
+}
+
+// OnError is a server.OnErrorHookFunc hook that forgets the id of a request that failed
+// before reaching the tool handler.
+func (c *Cancellations) OnError(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
+	if method != mcp.MethodToolsCall {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pending, ctx)
+}
+
+// Middleware is a server.ToolHandlerMiddleware that makes tool calls cancellable.
+func (c *Cancellations) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		c.mu.Lock()
+		id, ok := c.pending[ctx]
+		delete(c.pending, ctx)
+		c.mu.Unlock()
+		if !ok {
+			return next(ctx, request)
+		}
+
+		key := callKey(ctx, id)
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		c.mu.Lock()
+		c.calls[key] = cancel
+		c.mu.Unlock()
+
+		defer func() {
+			c.mu.Lock()
+			delete(c.calls, key)
+			c.mu.Unlock()
+		}()
+
+		return next(ctx, request)
+	}
+}
+
+// HandleCancelled is the server.NotificationHandlerFunc for notifications/cancelled.
+func (c *Cancellations) HandleCancelled(ctx context.Context, notification mcp.JSONRPCNotification) {
+	requestID, ok := notification.Params.AdditionalFields["requestId"]
+	if !ok {
+		return
+	}
+
+	cause := errRequestCancelled
+	if reason, ok := notification.Params.AdditionalFields["reason"].(string); ok && reason != "" {
+		cause = fmt.Errorf("%w: %s", errRequestCancelled, reason)
+	}
+
+	c.mu.Lock()
+	cancel, ok := c.calls[callKey(ctx, mcp.NewRequestId(requestID).String())]
+	c.mu.Unlock()
+
+	if ok {
+		cancel(cause)
+	}
+}
+
+// callKey identifies a request, request ids are only unique within a session.
+func callKey(ctx context.Context, requestID string) string {
+	var sessionID string
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionID = session.SessionID()
+	}
+	return sessionID + "/" + requestID
+}
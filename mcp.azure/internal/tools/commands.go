@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// readOnlyPrefixes are command name prefixes used by child extensions for commands that only read state.
+var readOnlyPrefixes = []string{"list-", "show-", "get-", "describe-", "check-"}
+
+// writePrefixes are command name prefixes used by child extensions for commands that change state.
+var writePrefixes = []string{"delete-", "create-", "set-", "update-", "remove-", "add-", "deploy-", "start-", "stop-", "restart-", "purge-"}
+
+// findCommand returns the child tool with the given command name.
+func findCommand(commands []mcp.Tool, name string) (mcp.Tool, bool) {
+	for _, command := range commands {
+		if command.Name == name {
+			return command, true
+		}
+	}
+
+	return mcp.Tool{}, false
+}
+
+// isIdempotent reports whether a command can safely be repeated after a failed attempt.
+// Explicit annotations win, read-only commands being idempotent whatever their idempotentHint,
+// otherwise read-only command names are treated as idempotent.
+func isIdempotent(command mcp.Tool) bool {
+	if !hasDefaultAnnotations(command) {
+		if hint := command.Annotations.ReadOnlyHint; hint != nil && *hint {
+			return true
+		}
+		if hint := command.Annotations.IdempotentHint; hint != nil {
+			return *hint
+		}
+		if hint := command.Annotations.ReadOnlyHint; hint != nil {
+			return false
+		}
+	}
+
+	return hasReadOnlyName(command.Name)
+}
+
+// destructiveNames are the parts of command names used by child extensions for commands that
+// delete state, either as a prefix such as "delete-keyvault" or a suffix such as "role-assignment-delete".
+var destructiveNames = []string{"delete", "purge", "remove"}
+
+// isDestructive reports whether a command deletes state and has to be confirmed by the user.
+// Explicit annotations win, otherwise the command name decides.
+func isDestructive(command mcp.Tool) bool {
+	if !hasDefaultAnnotations(command) {
+		if hint := command.Annotations.ReadOnlyHint; hint != nil && *hint {
+			return false
+		}
+		if hint := command.Annotations.DestructiveHint; hint != nil {
+			return *hint
+		}
+	}
+
+	for _, name := range destructiveNames {
+		if strings.HasPrefix(command.Name, name+"-") || strings.HasSuffix(command.Name, "-"+name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// classifyCommand reports whether a command only reads state, and why.
+// Explicit annotations win, otherwise the command name decides. Commands that are
+// neither annotated nor follow a naming convention are treated as writes.
+func classifyCommand(command mcp.Tool) (bool, string) {
+	if !hasDefaultAnnotations(command) {
+		if hint := command.Annotations.ReadOnlyHint; hint != nil {
+			if *hint {
+				return true, "annotated with readOnlyHint"
+			}
+			return false, "annotated with readOnlyHint false"
+		}
+		if hint := command.Annotations.DestructiveHint; hint != nil && *hint {
+			return false, "annotated with destructiveHint"
+		}
+	}
+
+	if prefix, ok := namePrefix(command.Name, writePrefixes); ok {
+		return false, fmt.Sprintf("name starts with '%s'", prefix)
+	}
+	if prefix, ok := namePrefix(command.Name, readOnlyPrefixes); ok {
+		return true, fmt.Sprintf("name starts with '%s'", prefix)
+	}
+
+	return false, "not known to be read-only"
+}
+
+// hasReadOnlyName reports whether the command name follows a read-only naming convention.
+func hasReadOnlyName(name string) bool {
+	_, ok := namePrefix(name, readOnlyPrefixes)
+	return ok
+}
+
+// namePrefix returns the first of the prefixes the command name starts with.
+func namePrefix(name string, prefixes []string) (string, bool) {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return prefix, true
+		}
+	}
+
+	return "", false
+}
+
+// hasDefaultAnnotations reports whether the command carries the annotations mcp.NewTool
+// fills in by default. Those say nothing about the command, so they are ignored.
+func hasDefaultAnnotations(command mcp.Tool) bool {
+	a := command.Annotations
+	return a.ReadOnlyHint != nil && !*a.ReadOnlyHint &&
+		a.DestructiveHint != nil && *a.DestructiveHint &&
+		a.IdempotentHint != nil && !*a.IdempotentHint &&
+		a.OpenWorldHint != nil && *a.OpenWorldHint
+}
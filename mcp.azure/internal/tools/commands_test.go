@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// annotated returns a command with only the given annotations set.
+func annotated(name string, annotations mcp.ToolAnnotation) mcp.Tool {
+	return mcp.Tool{Name: name, Annotations: annotations}
+}
+
+var (
+	yes = mcp.ToBoolPtr(true)
+	no  = mcp.ToBoolPtr(false)
+)
+
+func TestIsIdempotent(t *testing.T) {
+	tests := []struct {
+		name    string
+		command mcp.Tool
+		want    bool
+	}{
+		{"read-only name", annotated("list-keyvaults", mcp.ToolAnnotation{}), true},
+		{"write name", annotated("create-keyvault", mcp.ToolAnnotation{}), false},
+		{"unknown name", annotated("keyvault", mcp.ToolAnnotation{}), false},
+		{"default annotations use the name", mcp.NewTool("get-secret"), true},
+		{"default annotations of a write", mcp.NewTool("set-secret"), false},
+		{"idempotent hint", annotated("set-secret", mcp.ToolAnnotation{IdempotentHint: yes}), true},
+		{"idempotent hint false wins over name", annotated("get-token", mcp.ToolAnnotation{IdempotentHint: no}), false},
+		{"read-only hint", annotated("run-query", mcp.ToolAnnotation{ReadOnlyHint: yes}), true},
+		{"read-only hint wins over idempotent hint false", annotated("run-query", mcp.ToolAnnotation{ReadOnlyHint: yes, IdempotentHint: no}), true},
+		{"read-only hint false wins over name", annotated("get-token", mcp.ToolAnnotation{ReadOnlyHint: no}), false},
+		{"read-only hint set with NewTool", mcp.NewTool("run-query", mcp.WithReadOnlyHintAnnotation(true)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isIdempotent(tt.command); got != tt.want {
+				t.Errorf("isIdempotent(%s) = %v, want %v", tt.command.Name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsDestructive(t *testing.T) {
+	tests := []struct {
+		name    string
+		command mcp.Tool
+		want    bool
+	}{
+		{"delete prefix", annotated("delete-keyvault", mcp.ToolAnnotation{}), true},
+		{"purge prefix", annotated("purge-keyvault", mcp.ToolAnnotation{}), true},
+		{"delete suffix", annotated("role-assignment-delete", mcp.ToolAnnotation{}), true},
+		{"delete in the middle", annotated("list-deleted-keyvaults", mcp.ToolAnnotation{}), false},
+		{"write name", annotated("create-keyvault", mcp.ToolAnnotation{}), false},
+		{"default annotations use the name", mcp.NewTool("create-keyvault"), false},
+		{"default annotations of a delete", mcp.NewTool("delete-keyvault"), true},
+		{"destructive hint", annotated("reset-keys", mcp.ToolAnnotation{DestructiveHint: yes}), true},
+		{"destructive hint false wins over name", annotated("remove-tag", mcp.ToolAnnotation{DestructiveHint: no}), false},
+		{"read-only hint wins over destructive hint", annotated("delete-preview", mcp.ToolAnnotation{ReadOnlyHint: yes, DestructiveHint: yes}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDestructive(tt.command); got != tt.want {
+				t.Errorf("isDestructive(%s) = %v, want %v", tt.command.Name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    mcp.Tool
+		wantRead   bool
+		wantReason string
+	}{
+		{"read-only name", annotated("list-keyvaults", mcp.ToolAnnotation{}), true, "name starts with 'list-'"},
+		{"write name", annotated("set-secret", mcp.ToolAnnotation{}), false, "name starts with 'set-'"},
+		{"unknown name", annotated("keyvault", mcp.ToolAnnotation{}), false, "not known to be read-only"},
+		{"default annotations use the name", mcp.NewTool("show-secret"), true, "name starts with 'show-'"},
+		{"read-only hint", annotated("run-query", mcp.ToolAnnotation{ReadOnlyHint: yes}), true, "annotated with readOnlyHint"},
+		{"read-only hint false wins over name", annotated("get-token", mcp.ToolAnnotation{ReadOnlyHint: no}), false, "annotated with readOnlyHint false"},
+		{"destructive hint wins over name", annotated("get-and-reset", mcp.ToolAnnotation{DestructiveHint: yes}), false, "annotated with destructiveHint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read, reason := classifyCommand(tt.command)
+			if read != tt.wantRead || reason != tt.wantReason {
+				t.Errorf("classifyCommand(%s) = %v, %q, want %v, %q", tt.command.Name, read, reason, tt.wantRead, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp.azure/internal/metadata"
+)
+
+// confirmTimeout bounds how long the user has to answer a confirmation.
+const confirmTimeout = 5 * time.Minute
+
+// elicitConfirm is a metadata.ConfirmFunc that asks the user of the calling session
+// through elicitation. It fails when the client does not support elicitation.
+func elicitConfirm(ctx context.Context, message string) (bool, error) {
+	if !supportsElicitation(ctx) {
+		return false, metadata.ErrNoConfirm
+	}
+	s := server.ServerFromContext(ctx)
+	if s == nil {
+		return false, metadata.ErrNoConfirm
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, confirmTimeout)
+	defer cancel()
+
+	result, err := s.RequestElicitation(ctx, mcp.ElicitationRequest{
+		Params: mcp.ElicitationParams{
+			Message: message,
+			RequestedSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"confirm": map[string]any{
+						"type":        "boolean",
+						"title":       "Confirm",
+						"description": message,
+					},
+				},
+				"required": []string{"confirm"},
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	if result.Action != mcp.ElicitationResponseActionAccept {
+		return false, nil
+	}
+
+	content, _ := result.Content.(map[string]any)
+	confirmed, _ := content["confirm"].(bool)
+	return confirmed, nil
+}
+
+// supportsElicitation reports whether the calling client advertised the elicitation capability.
+func supportsElicitation(ctx context.Context) bool {
+	session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo)
+	if !ok {
+		return false
+	}
+	if _, ok := session.(server.SessionWithElicitation); !ok {
+		return false
+	}
+
+	return session.GetClientCapabilities().Elicitation != nil
+}
@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// setContextCommand is the command of the root tool that sets the default subscription and
+// tenant of the session.
+const setContextCommand = "set-context"
+
+// callContext is the subscription and tenant a command runs in.
+type callContext struct {
+	Subscription string `json:"subscription,omitempty"`
+	Tenant       string `json:"tenant,omitempty"`
+}
+
+// contextField is a field of the call context and the names, lower case and without
+// separators, of the parameters child commands declare for it.
+type contextField struct {
+	name       string
+	parameters []string
+	value      func(callContext) string
+}
+
+var contextFields = []contextField{
+	{
+		name:       "subscription",
+		parameters: []string{"subscription", "subscriptionid"},
+		value:      func(c callContext) string { return c.Subscription },
+	},
+	{
+		name:       "tenant",
+		parameters: []string{"tenant", "tenantid"},
+		value:      func(c callContext) string { return c.Tenant },
+	},
+}
+
+// sessionContexts holds the default call context of every session.
+type sessionContexts struct {
+	mu       sync.Mutex
+	contexts map[string]callContext
+}
+
+func newSessionContexts() *sessionContexts {
+	return &sessionContexts{
+		contexts: make(map[string]callContext),
+	}
+}
+
+func (s *sessionContexts) get(session string) callContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.contexts[session]
+}
+
+func (s *sessionContexts) set(session string, c callContext) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c == (callContext{}) {
+		delete(s.contexts, session)
+		return
+	}
+	s.contexts[session] = c
+}
+
+// EndSession forgets the default call context of a session that has ended.
+func (a *AzureTool) EndSession(sessionID string) {
+	a.contexts.set(sessionID, callContext{})
+}
+
+// setContext stores the subscription and tenant of the call as the defaults of the session.
+// An empty value clears the default, an omitted one keeps it.
+func (a *AzureTool) setContext(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	session := sessionID(ctx)
+	current := a.contexts.get(session)
+	if subscription, ok := request.GetArguments()["subscription"].(string); ok {
+		current.Subscription = strings.TrimSpace(subscription)
+	}
+	if tenant, ok := request.GetArguments()["tenant"].(string); ok {
+		current.Tenant = strings.TrimSpace(tenant)
+	}
+	a.contexts.set(session, current)
+
+	text := fmt.Sprintf(`
+		The session context is now: subscription %s, tenant %s.
+		Commands that take a subscription or tenant parameter get them, unless the call passes its own "subscription", "tenant" or parameters.
+	`, orDefault(current.Subscription), orDefault(current.Tenant))
+
+	return mcp.NewToolResultStructured(current, text), nil
+}
+
+// withContext injects the subscription and tenant of the call, or else of the session, into the
+// parameters the command declares for them. Parameters passed to the command are kept.
+// It returns the parameters to run the command with, notes about session defaults the command
+// cannot take, and the fields given for the call that the command cannot take.
+func (a *AzureTool) withContext(ctx context.Context, opts dispatchOptions, command mcp.Tool, params any) (any, []string, []string) {
+	session := a.contexts.get(sessionID(ctx))
+	if opts.context == (callContext{}) && session == (callContext{}) {
+		return params, nil, nil
+	}
+
+	args, ok := params.(map[string]any)
+	if params != nil && !ok {
+		return params, nil, nil
+	}
+	args = maps.Clone(args)
+	if args == nil {
+		args = map[string]any{}
+	}
+
+	properties, _ := inputSchema(command)["properties"].(map[string]any)
+
+	var notes, unsupported []string
+	for _, field := range contextFields {
+		value, explicit := field.value(opts.context), true
+		if value == "" {
+			value, explicit = field.value(session), false
+		}
+		if value == "" {
+			continue
+		}
+
+		parameter := contextParameter(properties, field.parameters)
+		switch {
+		case parameter == "" && explicit:
+			unsupported = append(unsupported, field.name)
+		case parameter == "":
+			notes = append(notes, fmt.Sprintf("Command %s does not take a %s, the %s %s of the session was not applied.", command.Name, field.name, field.name, value))
+		case args[parameter] == nil || args[parameter] == "":
+			args[parameter] = value
+		}
+	}
+
+	return args, notes, unsupported
+}
+
+// contextParameter returns the parameter of a command that takes a field of the call context,
+// matching names such as "subscription", "subscriptionId" or "subscription-id". The names are
+// tried in order, so a command taking several of them always gets the same one.
+func contextParameter(properties map[string]any, names []string) string {
+	parameters := slices.Sorted(maps.Keys(properties))
+	for _, n := range names {
+		for _, name := range parameters {
+			if strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(name)) == n {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
+
+// withNotes appends notes to the text of a result.
+func withNotes(result *mcp.CallToolResult, notes []string) *mcp.CallToolResult {
+	if len(notes) == 0 || result == nil {
+		return result
+	}
+
+	result.Content = append(result.Content, mcp.NewTextContent("Note: "+strings.Join(notes, "\nNote: ")))
+	return result
+}
+
+func contextUnsupportedResult(toolName string, commandName string, fields []string) *mcp.CallToolResult {
+	names := strings.Join(fields, " and ")
+	message := fmt.Sprintf("command %s of tool %s does not take a %s, it would run in its default instead", commandName, toolName, names)
+	nextAction := fmt.Sprintf(`Run again without "%s" to use the default of the tool, or use a command that takes a %s.`, strings.Join(fields, `" and "`), names)
+
+	return errorResult(toolError{
+		Code:       codeContextUnsupported,
+		Tool:       toolName,
+		Command:    commandName,
+		Message:    message,
+		NextAction: nextAction,
+	}, fmt.Sprintf(`
+		Command %s of tool %s was not run: %s.
+		%s
+	`, commandName, toolName, message, nextAction))
+}
+
+func orDefault(value string) string {
+	if value == "" {
+		return "(default)"
+	}
+	return value
+}
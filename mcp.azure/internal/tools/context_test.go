@@ -0,0 +1,35 @@
+package tools
+
+import "testing"
+
+func TestContextParameter(t *testing.T) {
+	names := []string{"subscription", "subscriptionid"}
+
+	tests := []struct {
+		name       string
+		properties []string
+		want       string
+	}{
+		{"exact name", []string{"subscription", "resourceGroup"}, "subscription"},
+		{"camel case", []string{"subscriptionId"}, "subscriptionId"},
+		{"kebab case", []string{"subscription-id"}, "subscription-id"},
+		{"first name wins", []string{"subscriptionId", "subscription"}, "subscription"},
+		{"not taken", []string{"resourceGroup"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			properties := make(map[string]any, len(tt.properties))
+			for _, p := range tt.properties {
+				properties[p] = map[string]any{"type": "string"}
+			}
+
+			// Maps are iterated in random order, the result must not depend on it.
+			for range 20 {
+				if got := contextParameter(properties, names); got != tt.want {
+					t.Fatalf("contextParameter(%v) = %q, want %q", tt.properties, got, tt.want)
+				}
+			}
+		})
+	}
+}
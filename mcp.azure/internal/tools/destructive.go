@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp.azure/internal/audit"
+	"mcp.azure/internal/metadata"
+)
+
+// confirmTokenTTL is how long a confirm token returned by a dry run can be used.
+const confirmTokenTTL = 10 * time.Minute
+
+// confirmTokens are issued by dry runs of destructive commands for clients that cannot elicit.
+// A token confirms exactly one call of the same command with the same parameters in the
+// same session.
+type confirmTokens struct {
+	mu     sync.Mutex
+	tokens map[string]confirmToken
+}
+
+type confirmToken struct {
+	session string
+	digest  string
+	expires time.Time
+}
+
+func newConfirmTokens() *confirmTokens {
+	return &confirmTokens{
+		tokens: make(map[string]confirmToken),
+	}
+}
+
+// issue returns a new token for the command call.
+func (c *confirmTokens) issue(session string, toolName string, request mcp.CallToolRequest) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to create confirm token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for t, ct := range c.tokens {
+		if now.After(ct.expires) {
+			delete(c.tokens, t)
+		}
+	}
+	c.tokens[token] = confirmToken{
+		session: session,
+		digest:  callDigest(toolName, request),
+		expires: now.Add(confirmTokenTTL),
+	}
+
+	return token, nil
+}
+
+// redeem uses up the token and reports whether it confirms the command call.
+func (c *confirmTokens) redeem(token string, session string, toolName string, request mcp.CallToolRequest) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ct, ok := c.tokens[token]
+	if !ok {
+		return false
+	}
+	delete(c.tokens, token)
+
+	return ct.session == session && ct.digest == callDigest(toolName, request) && time.Now().Before(ct.expires)
+}
+
+// callDigest identifies a command call by its tool, command and parameters.
+// Maps are marshaled with sorted keys, so equal parameters have equal digests.
+func callDigest(toolName string, request mcp.CallToolRequest) string {
+	params, _ := json.Marshal(request.Params.Arguments)
+	sum := sha256.Sum256([]byte(toolName + "\x00" + request.Params.Name + "\x00" + string(params)))
+	return hex.EncodeToString(sum[:])
+}
+
+// confirmDestructive asks the user to confirm a destructive command through elicitation.
+// Clients that cannot elicit have to pass the confirm token returned by a dry run of the
+// same call instead. A nil result means the command may run.
+func (a *AzureTool) confirmDestructive(ctx context.Context, tm metadata.ToolMetadata, opts dispatchOptions, request mcp.CallToolRequest) *mcp.CallToolResult {
+	toolName := tm.Metadata().Name
+	commandName := request.Params.Name
+
+	if !supportsElicitation(ctx) {
+		if opts.confirm != "" && a.confirmations.redeem(opts.confirm, sessionID(ctx), toolName, request) {
+			return nil
+		}
+		return confirmationRequiredResult(toolName, opts, request, opts.confirm != "")
+	}
+
+	ok, err := elicitConfirm(ctx, fmt.Sprintf(
+		"Run the destructive command %s of tool %s? This cannot be undone.\n\nTarget:\n%s",
+		commandName, toolName, describeTarget(commandName, request.Params.Arguments),
+	))
+	switch {
+	case errors.Is(err, metadata.ErrNoConfirm):
+		return confirmationRequiredResult(toolName, opts, request, false)
+	case err != nil:
+		return callErrorResult(toolName, commandName, fmt.Errorf("failed to ask for confirmation: %w", err))
+	case !ok:
+		return errorResult(toolError{
+			Code:       codeConfirmationDeclined,
+			Tool:       toolName,
+			Command:    commandName,
+			Message:    fmt.Sprintf("the user declined to run command %s of tool %s", commandName, toolName),
+			NextAction: "Do not run the command again unless the user asks for it.",
+		}, fmt.Sprintf("The user declined to run command %s of tool %s. Do not run it again unless the user asks for it.", commandName, toolName))
+	}
+
+	return nil
+}
+
+// describeTarget lists the parameters of a command, one per line and with secrets redacted,
+// so the user can see exactly which resource a destructive command applies to.
+func describeTarget(commandName string, params any) string {
+	args, ok := audit.Redact(commandName, params).(map[string]any)
+	if !ok || len(args) == 0 {
+		return "  (no parameters)"
+	}
+
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var target strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&target, "  %s: %v\n", name, args[name])
+	}
+
+	return strings.TrimSuffix(target.String(), "\n")
+}
+
+// confirmationRequiredResult tells the agent how to get a confirm token for the call. Plan steps
+// whose parameters reference earlier steps are confirmed with their resolved parameters, which
+// are only known once the earlier steps ran, and flat tools whose command takes its own "confirm"
+// or "dryRun" parameter are confirmed through the azure tool.
+func confirmationRequiredResult(toolName string, opts dispatchOptions, request mcp.CallToolRequest, invalidToken bool) *mcp.CallToolResult {
+	commandName := request.Params.Name
+	message := fmt.Sprintf("command %s of tool %s is destructive and has to be confirmed by the user", commandName, toolName)
+	if invalidToken {
+		message = fmt.Sprintf("the confirm token does not match command %s of tool %s with these parameters, or it was already used or expired", commandName, toolName)
+	}
+
+	var nextAction string
+	switch {
+	case opts.references:
+		params, _ := json.Marshal(audit.Redact(commandName, request.Params.Arguments))
+		nextAction = fmt.Sprintf(`The parameters of this plan step reference earlier steps, so it could not be confirmed in advance. `+
+			`Run the remaining steps again as a new plan, starting with this step with its resolved parameters %s, first with "dryRun" set to true. `+
+			`Show the returned plan to the user and, only once they agree, run it again with the returned "confirm" token on the step.`, params)
+	case opts.flat:
+		nextAction = fmt.Sprintf(`Call the azure tool with tool %q, command %q, the same "parameters" and "dryRun" set to true, show the returned plan to the user and, `+
+			`only once they agree, run it again with the returned "confirm" token.`, toolName, commandName)
+	default:
+		nextAction = `Run the same call again with "dryRun" set to true, show the returned plan to the user and, only once they agree, run it again with the returned "confirm" token.`
+	}
+
+	return errorResult(toolError{
+		Code:       codeConfirmationRequired,
+		Tool:       toolName,
+		Command:    commandName,
+		Message:    message,
+		NextAction: nextAction,
+	}, fmt.Sprintf(`
+		Command %s of tool %s was not run: %s.
+		%s
+	`, commandName, toolName, message, nextAction))
+}
@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp.azure/internal/doctor"
+	"mcp.azure/internal/metadata"
+)
+
+// diagnose checks azd, az, the extensions, credentials and every child tool of the root server,
+// and returns the report so agents can tell why a tool fails and what the user has to fix.
+func (a *AzureTool) diagnose(ctx context.Context) (*mcp.CallToolResult, error) {
+	allTools := make([]metadata.ToolMetadata, 0, len(a.childTools))
+	for _, t := range a.childTools {
+		allTools = append(allTools, a.toolMetadataMap[t.Name])
+	}
+
+	report := doctor.New(allTools, doctor.WithChecks(doctor.DuplicateChecks(a.duplicates)...)).Run(ctx)
+
+	var text strings.Builder
+	if err := report.WriteText(&text); err != nil {
+		return nil, fmt.Errorf("failed to write diagnostics report: %w", err)
+	}
+
+	return mcp.NewToolResultStructured(report, text.String()), nil
+}
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp.azure/internal/metadata"
+	"mcp.azure/internal/pool"
+)
+
+// Stable codes of the error results returned by the root server.
+const (
+	codeToolNotFound      = "tool_not_found"
+	codeCommandNotFound   = "command_not_found"
+	codeClientStartFailed = "client_start_failed"
+	codeInvalidParameters = "invalid_parameters"
+	codeChildError        = "child_error"
+	codeAuthRequired      = "auth_required"
+	codeReadOnly          = "read_only"
+
+	codeContextUnsupported = "context_unsupported"
+
+	codeConfirmationRequired = "confirmation_required"
+	codeConfirmationDeclined = "confirmation_declined"
+)
+
+// toolError is the structured content of an error result, so agents can tell a failure
+// from a result and decide what to do next without parsing the text.
+type toolError struct {
+	Code    string `json:"code"`
+	Tool    string `json:"tool,omitempty"`
+	Command string `json:"command,omitempty"`
+	Message string `json:"message"`
+	// NextAction suggests how the agent can recover from the error.
+	NextAction string `json:"nextAction"`
+
+	// Problems and Schema are set for invalid_parameters.
+	Problems []parameterProblem `json:"problems,omitempty"`
+	Schema   map[string]any     `json:"schema,omitempty"`
+	// Commands lists the available commands for command_not_found.
+	Commands []string `json:"commands,omitempty"`
+}
+
+// errorResult returns an error result carrying the structured error next to its human readable text.
+func errorResult(e toolError, text string) *mcp.CallToolResult {
+	result := mcp.NewToolResultStructured(e, text)
+	result.IsError = true
+	return result
+}
+
+// callErrorResult describes a failed command call, or a failure to list the commands, to the agent.
+func callErrorResult(toolName string, commandName string, err error) *mcp.CallToolResult {
+	if isAuthError(err) {
+		nextAction := `Sign in with "az login" and "azd auth login", or complete the sign-in of the tool, then run again.`
+		if errors.Is(err, metadata.ErrCredentialsUnavailable) {
+			nextAction = `Ask the user to provide the credentials named in the error, such as by setting the environment variable or signing in with "az login", then run again.`
+		}
+
+		return errorResult(toolError{
+			Code:       codeAuthRequired,
+			Tool:       toolName,
+			Command:    commandName,
+			Message:    err.Error(),
+			NextAction: nextAction,
+		}, fmt.Sprintf(`
+			Authentication is required to call tool: %s, command: %s, Error: %v
+
+			%s
+		`, toolName, commandName, err, nextAction))
+	}
+
+	var startErr *pool.StartError
+	if errors.As(err, &startErr) {
+		nextAction := "Check that the tool is installed and can be started, then run again."
+		if errors.Is(err, metadata.ErrInstallNotApproved) {
+			nextAction = "Ask the user to install the extension with 'azd ext install', or to allow it in the extension policy, then run again."
+		}
+		if errors.Is(err, metadata.ErrServerNotApproved) {
+			nextAction = "Ask the user to approve the server with 'azd mcp azure server trust --server <name>', or to allow it in the server policy, then run again."
+		}
+
+		return errorResult(toolError{
+			Code:       codeClientStartFailed,
+			Tool:       toolName,
+			Command:    commandName,
+			Message:    startErr.Err.Error(),
+			NextAction: nextAction,
+		}, fmt.Sprintf("Failed to start tool client: %v", startErr.Err))
+	}
+
+	nextAction := `Run again with the "learn" argument and the "tool" name to get a list of available tools and their parameters.`
+	if errors.Is(err, pool.ErrChildExited) {
+		nextAction = "The tool server exited and is restarted on the next call. Run again if the command is safe to repeat."
+	}
+
+	return errorResult(toolError{
+		Code:       codeChildError,
+		Tool:       toolName,
+		Command:    commandName,
+		Message:    err.Error(),
+		NextAction: nextAction,
+	}, fmt.Sprintf(`
+		There was an error finding or calling tool and command.
+		Failed to call tool: %s, command: %s, Error: %v
+
+		%s
+	`, toolName, commandName, err, nextAction))
+}
+
+// isAuthError reports whether the call failed because the user is not signed in, either to
+// an external server using OAuth or to the Azure CLIs used by the extensions, or because the
+// credentials of an external server could not be acquired.
+func isAuthError(err error) bool {
+	if client.IsOAuthAuthorizationRequiredError(err) || errors.Is(err, metadata.ErrCredentialsUnavailable) {
+		return true
+	}
+
+	msg := err.Error()
+	for _, s := range []string{"az login", "azd auth login", "not logged in"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func commandNotFoundResult(toolName string, commandName string, commands []mcp.Tool) *mcp.CallToolResult {
+	names := make([]string, 0, len(commands))
+	for _, command := range commands {
+		names = append(names, command.Name)
+	}
+
+	return errorResult(toolError{
+		Code:       codeCommandNotFound,
+		Tool:       toolName,
+		Command:    commandName,
+		Message:    fmt.Sprintf("command %s not found for tool %s", commandName, toolName),
+		NextAction: `Pick one of the available commands, or run again with the "learn" argument and the "tool" name to get the commands and their parameters.`,
+		Commands:   names,
+	}, fmt.Sprintf(`
+		Command %s not found for tool %s
+		Available commands: %s
+		Run again with the "learn" argument and the "tool" name to get the commands and their parameters.
+	`, commandName, toolName, strings.Join(names, ", ")))
+}
+
+func toolNotFoundResult(toolName string) *mcp.CallToolResult {
+	return errorResult(toolError{
+		Code:       codeToolNotFound,
+		Tool:       toolName,
+		Message:    fmt.Sprintf("tool %s not found", toolName),
+		NextAction: `Run again with the "learn" argument and empty "tool" to get a list of available tools.`,
+	}, fmt.Sprintf(`
+		Tool %s not found
+		Run again with the "learn" argument and empty "tool" to get a list of available tools and their parameters.
+	`, toolName))
+}
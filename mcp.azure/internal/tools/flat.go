@@ -0,0 +1,157 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp.azure/internal/metadata"
+)
+
+// Arguments of destructive flat tools that are taken by the root server, as those of the azure tool.
+const (
+	flatDryRunArgument  = "dryRun"
+	flatConfirmArgument = "confirm"
+)
+
+// FlatTools exposes every child command as a first-class "<tool>.<command>" tool
+// carrying the child's input schema, for MCP clients that work better with real
+// tools than with the learn, tool and command indirection of the "azure" tool.
+//
+// Commands are registered lazily: cached schemas are registered up front and the
+// commands of every other child tool once they are first listed through the "azure"
+// tool. Registering new commands notifies clients that the tool list changed.
+type FlatTools struct {
+	azure  *AzureTool
+	server *server.MCPServer
+
+	mu         sync.Mutex
+	registered map[string]bool
+}
+
+// NewFlatTools creates the flat tools over the root tool and subscribes to the
+// commands it lists.
+func NewFlatTools(azure *AzureTool, s *server.MCPServer) *FlatTools {
+	f := &FlatTools{
+		azure:      azure,
+		server:     s,
+		registered: make(map[string]bool),
+	}
+	azure.onCommands = f.register
+
+	return f
+}
+
+// Preload registers the commands of every child tool whose schemas are cached,
+// without starting any child server.
+func (f *FlatTools) Preload() {
+	for _, tm := range f.azure.toolMetadataMap {
+		if commands, ok := f.azure.schemas.Tools(tm); ok {
+			f.register(tm, commands)
+		}
+	}
+}
+
+// register adds the commands of a child tool the first time they are seen.
+func (f *FlatTools) register(tm metadata.ToolMetadata, commands []mcp.Tool) {
+	toolName := tm.Metadata().Name
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.registered[toolName] {
+		return
+	}
+	f.registered[toolName] = true
+
+	serverTools := make([]server.ServerTool, 0, len(commands))
+	for _, command := range commands {
+		if readOnly, _ := classifyCommand(command); f.azure.readOnly && !readOnly {
+			continue
+		}
+
+		flatTool := command
+		flatTool.Name = flatToolName(toolName, command.Name)
+		confirmable := false
+		if isDestructive(command) {
+			flatTool, confirmable = withConfirmArguments(flatTool)
+		}
+
+		serverTools = append(serverTools, server.ServerTool{
+			Tool:    flatTool,
+			Handler: f.handler(tm, command.Name, confirmable),
+		})
+	}
+
+	if len(serverTools) > 0 {
+		f.server.AddTools(serverTools...)
+	}
+}
+
+// handler dispatches calls of a flat tool to the child command it was created from.
+// The "dryRun" and "confirm" arguments of a confirmable tool are taken by the root server,
+// as with the azure tool, and not passed to the child.
+func (f *FlatTools) handler(tm metadata.ToolMetadata, commandName string, confirmable bool) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		opts := dispatchOptions{flat: true}
+		params := request.Params.Arguments
+		if confirmable {
+			args := maps.Clone(request.GetArguments())
+			opts.dryRun, _ = args[flatDryRunArgument].(bool)
+			opts.confirm, _ = args[flatConfirmArgument].(string)
+			delete(args, flatDryRunArgument)
+			delete(args, flatConfirmArgument)
+			params = args
+		}
+
+		return f.azure.dispatch(ctx, tm, opts, commandRequest(request, commandName, params)), nil
+	}
+}
+
+// withConfirmArguments adds the "dryRun" and "confirm" arguments to the schema of a destructive
+// flat tool, so clients that cannot elicit can confirm it. It reports false, leaving the tool as
+// it is, when the command takes parameters of the same names, which then have to be confirmed
+// through the azure tool.
+func withConfirmArguments(tool mcp.Tool) (mcp.Tool, bool) {
+	schema := inputSchema(tool)
+	if schema == nil {
+		return tool, false
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	if properties == nil {
+		properties = map[string]any{}
+	}
+	if _, ok := properties[flatDryRunArgument]; ok {
+		return tool, false
+	}
+	if _, ok := properties[flatConfirmArgument]; ok {
+		return tool, false
+	}
+
+	properties[flatDryRunArgument] = map[string]any{
+		"type":        "boolean",
+		"description": "Validate the call and return what would run, with a confirm token, without running it.",
+	}
+	properties[flatConfirmArgument] = map[string]any{
+		"type":        "string",
+		"description": "The confirm token returned by a dry run of the same call, once the user agreed to run it.",
+	}
+	schema["properties"] = properties
+
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return tool, false
+	}
+	tool.InputSchema = mcp.ToolInputSchema{}
+	tool.RawInputSchema = raw
+
+	return tool, true
+}
+
+func flatToolName(toolName string, commandName string) string {
+	return toolName + "." + commandName
+}
@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp.azure/internal/audit"
+	"mcp.azure/internal/metadata"
+)
+
+// WithReadOnly refuses every command that is not known to only read state, so agents
+// can be pointed at production subscriptions without being able to change them.
+func WithReadOnly() Option {
+	return func(a *AzureTool) {
+		a.readOnly = true
+	}
+}
+
+// WithDryRun validates commands and returns what would have been run instead of running them.
+func WithDryRun() Option {
+	return func(a *AzureTool) {
+		a.dryRun = true
+	}
+}
+
+// dispatchPlan is the structured content of a dry-run result: the command that would have run.
+type dispatchPlan struct {
+	DryRun     bool   `json:"dryRun"`
+	Tool       string `json:"tool"`
+	Command    string `json:"command"`
+	Parameters any    `json:"parameters,omitempty"`
+	// Source is where the tool was registered, such as the azd extension that runs the command.
+	Source  string `json:"source"`
+	Version string `json:"version,omitempty"`
+	// ReadOnly and Classification tell whether the command only reads state and why.
+	ReadOnly       bool   `json:"readOnly"`
+	Classification string `json:"classification"`
+	// Destructive commands have to be confirmed by the user. Confirm is the token that
+	// confirms the same call for clients that cannot ask the user.
+	Destructive bool   `json:"destructive"`
+	Confirm     string `json:"confirm,omitempty"`
+}
+
+// modesDescription describes the enforced modes to agents, it is empty when no mode is enabled.
+func (a *AzureTool) modesDescription() string {
+	var description string
+	if a.readOnly {
+		description += "\nThe server is in read-only mode: commands that change state are refused, only use commands that read state.\n"
+	}
+	if a.dryRun {
+		description += "\nThe server is in dry-run mode: commands are validated and returned as a plan instead of being run.\n"
+	}
+	return description
+}
+
+func readOnlyResult(toolName string, commandName string, reason string) *mcp.CallToolResult {
+	return errorResult(toolError{
+		Code:       codeReadOnly,
+		Tool:       toolName,
+		Command:    commandName,
+		Message:    fmt.Sprintf("command %s of tool %s may change state (%s) and the server is in read-only mode", commandName, toolName, reason),
+		NextAction: "Use a command that only reads state, or ask the user to run the command outside of read-only mode.",
+	}, fmt.Sprintf(`
+		Command %s of tool %s was refused: the server is in read-only mode and the command may change state (%s).
+		Use a command that only reads state, or ask the user to run the command outside of read-only mode.
+	`, commandName, toolName, reason))
+}
+
+func dryRunResult(
+	tm metadata.ToolMetadata,
+	request mcp.CallToolRequest,
+	readOnly bool,
+	classification string,
+	destructive bool,
+	token string,
+) *mcp.CallToolResult {
+	plan := dispatchPlan{
+		DryRun:         true,
+		Tool:           tm.Metadata().Name,
+		Command:        request.Params.Name,
+		Parameters:     audit.Redact(request.Params.Name, request.Params.Arguments),
+		Source:         tm.Source(),
+		ReadOnly:       readOnly,
+		Classification: classification,
+		Destructive:    destructive,
+		Confirm:        token,
+	}
+	if azdTool, ok := tm.(*metadata.AzdToolMetadata); ok {
+		plan.Version = azdTool.VersionInfo().Version
+	}
+
+	planJson, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		planJson = []byte(err.Error())
+	}
+
+	text := fmt.Sprintf(`
+		Dry run: the command was validated but not run. The server would dispatch:
+
+		%s
+	`, string(planJson))
+	if token != "" {
+		text += fmt.Sprintf(`
+		The command is destructive. Show this plan to the user and, only once they agree to run it,
+		run the same call again without "dryRun" and with "confirm": "%s", set on the step when it is part of a plan.
+	`, token)
+	}
+
+	return mcp.NewToolResultStructured(plan, text)
+}
@@ -0,0 +1,324 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxPlanSteps bounds the number of steps of a single plan.
+const maxPlanSteps = 20
+
+// Statuses of the steps of a plan.
+const (
+	stepSucceeded = "success"
+	stepFailed    = "error"
+	stepSkipped   = "skipped"
+	stepDryRun    = "dry_run"
+)
+
+// stepReference matches references to the output of an earlier step, such as
+// "${steps.0.id}" or "${steps.1.properties.primaryEndpoints.blob}".
+var stepReference = regexp.MustCompile(`\$\{steps\.(\d+)((?:\.[^.}]+)*)\}`)
+
+// planStep is a single command of a plan.
+type planStep struct {
+	Tool       string         `json:"tool"`
+	Command    string         `json:"command"`
+	Parameters map[string]any `json:"parameters,omitempty"`
+	// Confirm is the confirm token returned for the step by a dry run of the plan.
+	Confirm string `json:"confirm,omitempty"`
+}
+
+// stepResult is the outcome of a single step in the combined result of a plan.
+type stepResult struct {
+	Step    int    `json:"step"`
+	Tool    string `json:"tool"`
+	Command string `json:"command"`
+	Status  string `json:"status"`
+	// Output is the output of the step that later steps can reference.
+	Output any        `json:"output,omitempty"`
+	Error  *toolError `json:"error,omitempty"`
+}
+
+// planResult is the structured content of the combined result of a plan.
+type planResult struct {
+	Steps     []stepResult `json:"steps"`
+	Completed int          `json:"completed"`
+	Failed    bool         `json:"failed"`
+}
+
+// runPlan runs the steps of a plan in order through the same dispatcher, and the same child
+// clients, as single commands. Parameters can reference the output of earlier steps, and
+// unless "stopOnError" is false the plan stops at the first step that fails.
+func (a *AzureTool) runPlan(ctx context.Context, request mcp.CallToolRequest, rawPlan any) (*mcp.CallToolResult, error) {
+	steps, problems := a.parsePlan(rawPlan)
+	if len(problems) > 0 {
+		return invalidPlanResult(problems), nil
+	}
+
+	stopOnError := true
+	if stop, ok := request.GetArguments()["stopOnError"].(bool); ok {
+		stopOnError = stop
+	}
+	opts := newDispatchOptions(request)
+	dryRun := opts.dryRun || a.dryRun
+
+	result := planResult{Steps: make([]stepResult, 0, len(steps))}
+	var text strings.Builder
+	for i, step := range steps {
+		sr := stepResult{Step: i, Tool: step.Tool, Command: step.Command}
+		if result.Failed && stopOnError {
+			sr.Status = stepSkipped
+			result.Steps = append(result.Steps, sr)
+			fmt.Fprintf(&text, "Step %d (%s %s): skipped\n", i, step.Tool, step.Command)
+			continue
+		}
+
+		stepOpts := opts
+		stepOpts.confirm = step.Confirm
+		stepOpts.references = stepReference.MatchString(marshalParameters(step.Parameters))
+
+		var res *mcp.CallToolResult
+		params, err := resolveReferences(step.Parameters, result.Steps)
+		switch {
+		case dryRun:
+			// Earlier steps do not run in a dry run, so references are left as they are.
+			res = a.dispatch(ctx, a.toolMetadataMap[step.Tool], stepOpts, commandRequest(request, step.Command, step.Parameters))
+		case err != nil:
+			res = errorResult(toolError{
+				Code:       codeInvalidParameters,
+				Tool:       step.Tool,
+				Command:    step.Command,
+				Message:    err.Error(),
+				NextAction: "Fix the reference to match the output of the earlier step and run the plan again.",
+			}, fmt.Sprintf("Failed to resolve the parameters of step %d: %v", i, err))
+		default:
+			res = a.dispatch(ctx, a.toolMetadataMap[step.Tool], stepOpts, commandRequest(request, step.Command, params))
+		}
+
+		sr.Output = stepOutput(res)
+		switch {
+		case res.IsError:
+			sr.Status = stepFailed
+			sr.Output = nil
+			if e, ok := res.StructuredContent.(toolError); ok {
+				sr.Error = &e
+			} else {
+				_, message := resultError(res)
+				sr.Error = &toolError{Code: codeChildError, Tool: step.Tool, Command: step.Command, Message: message}
+			}
+			result.Failed = true
+		case isDryRun(res):
+			sr.Status = stepDryRun
+			result.Completed++
+		default:
+			sr.Status = stepSucceeded
+			result.Completed++
+		}
+		result.Steps = append(result.Steps, sr)
+
+		fmt.Fprintf(&text, "Step %d (%s %s): %s\n", i, step.Tool, step.Command, sr.Status)
+		for _, content := range res.Content {
+			if textContent, ok := mcp.AsTextContent(content); ok {
+				text.WriteString(strings.TrimSpace(textContent.Text) + "\n")
+			}
+		}
+		text.WriteString("\n")
+	}
+
+	fmt.Fprintf(&text, "%d of %d steps completed.\n", result.Completed, len(steps))
+	if result.Failed && stopOnError {
+		text.WriteString("The plan stopped at the first step that failed, fix it and run the remaining steps again.\n")
+	}
+
+	combined := mcp.NewToolResultStructured(result, text.String())
+	combined.IsError = result.Failed
+	return combined, nil
+}
+
+// parsePlan reads the steps of a plan and checks that every step names a known tool and
+// only references the output of earlier steps, so nothing runs when the plan is malformed.
+func (a *AzureTool) parsePlan(rawPlan any) ([]planStep, []parameterProblem) {
+	raw, err := json.Marshal(rawPlan)
+	if err != nil {
+		return nil, []parameterProblem{{Path: "plan", Message: err.Error()}}
+	}
+
+	var steps []planStep
+	if err := json.Unmarshal(raw, &steps); err != nil {
+		return nil, []parameterProblem{{Path: "plan", Message: "expected a list of steps with tool, command and parameters: " + err.Error()}}
+	}
+	if len(steps) > maxPlanSteps {
+		return nil, []parameterProblem{{Path: "plan", Message: fmt.Sprintf("a plan has at most %d steps", maxPlanSteps)}}
+	}
+
+	var problems []parameterProblem
+	for i, step := range steps {
+		if step.Parameters == nil {
+			steps[i].Parameters = map[string]any{}
+		}
+
+		path := fmt.Sprintf("plan[%d]", i)
+		if step.Tool == "" {
+			problems = append(problems, parameterProblem{Path: path + ".tool", Message: "required"})
+		} else if _, ok := a.toolMetadataMap[step.Tool]; !ok {
+			problems = append(problems, parameterProblem{Path: path + ".tool", Message: fmt.Sprintf("tool %s not found", step.Tool)})
+		}
+		if step.Command == "" {
+			problems = append(problems, parameterProblem{Path: path + ".command", Message: "required"})
+		}
+
+		for _, match := range stepReference.FindAllStringSubmatch(marshalParameters(step.Parameters), -1) {
+			if ref, _ := strconv.Atoi(match[1]); ref >= i {
+				problems = append(problems, parameterProblem{
+					Path:    path + ".parameters",
+					Message: fmt.Sprintf("%s can only reference the output of an earlier step", match[0]),
+				})
+			}
+		}
+	}
+
+	return steps, problems
+}
+
+// marshalParameters returns the parameters of a step as JSON, to find the references in them.
+func marshalParameters(params map[string]any) string {
+	raw, _ := json.Marshal(params)
+	return string(raw)
+}
+
+// resolveReferences replaces references to the output of earlier steps in the parameters.
+// A string that is a single reference is replaced with the referenced value, keeping its type,
+// references within a longer string are replaced with their text.
+func resolveReferences(value any, steps []stepResult) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		resolved := make(map[string]any, len(v))
+		for name, item := range v {
+			r, err := resolveReferences(item, steps)
+			if err != nil {
+				return nil, err
+			}
+			resolved[name] = r
+		}
+		return resolved, nil
+	case []any:
+		resolved := make([]any, len(v))
+		for i, item := range v {
+			r, err := resolveReferences(item, steps)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	case string:
+		if match := stepReference.FindStringSubmatch(v); match != nil && match[0] == v {
+			return lookupReference(match, steps)
+		}
+
+		var refErr error
+		resolved := stepReference.ReplaceAllStringFunc(v, func(ref string) string {
+			referenced, err := lookupReference(stepReference.FindStringSubmatch(ref), steps)
+			if err != nil {
+				refErr = err
+				return ref
+			}
+			if s, ok := referenced.(string); ok {
+				return s
+			}
+			b, _ := json.Marshal(referenced)
+			return string(b)
+		})
+		return resolved, refErr
+	default:
+		return v, nil
+	}
+}
+
+// lookupReference returns the value a reference points to in the output of an earlier step.
+func lookupReference(match []string, steps []stepResult) (any, error) {
+	index, _ := strconv.Atoi(match[1])
+	if index >= len(steps) || steps[index].Status != stepSucceeded {
+		return nil, fmt.Errorf("%s references step %d, which did not succeed", match[0], index)
+	}
+
+	value := steps[index].Output
+	for _, key := range strings.Split(strings.TrimPrefix(match[2], "."), ".") {
+		if key == "" {
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			item, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("%s: the output of step %d has no '%s'", match[0], index, key)
+			}
+			value = item
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("%s: the output of step %d has no item '%s'", match[0], index, key)
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("%s: the output of step %d has no '%s'", match[0], index, key)
+		}
+	}
+
+	return value, nil
+}
+
+// stepOutput returns the output of a step that later steps can reference: its structured
+// content, or its text parsed as JSON when possible, or else the text itself.
+func stepOutput(result *mcp.CallToolResult) any {
+	if result.StructuredContent != nil {
+		raw, err := json.Marshal(result.StructuredContent)
+		if err == nil {
+			var output any
+			if err := json.Unmarshal(raw, &output); err == nil {
+				return output
+			}
+		}
+	}
+
+	for _, content := range result.Content {
+		textContent, ok := mcp.AsTextContent(content)
+		if !ok {
+			continue
+		}
+
+		text := strings.TrimSpace(textContent.Text)
+		var output any
+		if err := json.Unmarshal([]byte(text), &output); err == nil {
+			return output
+		}
+		return text
+	}
+
+	return nil
+}
+
+func invalidPlanResult(problems []parameterProblem) *mcp.CallToolResult {
+	var text strings.Builder
+	text.WriteString("Invalid plan, no step was run:\n")
+	for _, p := range problems {
+		text.WriteString("- " + p.String() + "\n")
+	}
+	text.WriteString(`
+Every step needs a "tool" and a "command", and parameters can only reference earlier steps, such as "${steps.0.id}".
+`)
+
+	return errorResult(toolError{
+		Code:       codeInvalidParameters,
+		Message:    fmt.Sprintf("%d problems in the plan", len(problems)),
+		NextAction: "Fix the plan and run again.",
+		Problems:   problems,
+	}, text.String())
+}
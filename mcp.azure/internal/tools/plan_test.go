@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"mcp.azure/internal/metadata"
+)
+
+func TestParsePlanReferences(t *testing.T) {
+	a := &AzureTool{toolMetadataMap: map[string]metadata.ToolMetadata{"storage": nil}}
+
+	tests := []struct {
+		name string
+		plan []any
+		want []parameterProblem
+	}{
+		{
+			name: "reference to an earlier step",
+			plan: []any{
+				map[string]any{"tool": "storage", "command": "create-account"},
+				map[string]any{"tool": "storage", "command": "create-container", "parameters": map[string]any{"account": "${steps.0.name}"}},
+			},
+		},
+		{
+			name: "reference to a later step",
+			plan: []any{
+				map[string]any{"tool": "storage", "command": "create-account", "parameters": map[string]any{"name": "${steps.1.name}"}},
+				map[string]any{"tool": "storage", "command": "create-container"},
+			},
+			want: []parameterProblem{{Path: "plan[0].parameters", Message: "${steps.1.name} can only reference the output of an earlier step"}},
+		},
+		{
+			name: "reference to the step itself",
+			plan: []any{
+				map[string]any{"tool": "storage", "command": "create-account", "parameters": map[string]any{"name": "${steps.0.name}"}},
+			},
+			want: []parameterProblem{{Path: "plan[0].parameters", Message: "${steps.0.name} can only reference the output of an earlier step"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := a.parsePlan(tt.plan)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePlan() problems = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveReferences(t *testing.T) {
+	steps := []stepResult{
+		{Status: stepSucceeded, Output: map[string]any{
+			"name":  "st1",
+			"count": float64(3),
+			"keys":  []any{map[string]any{"name": "key1"}},
+		}},
+		{Status: stepFailed},
+	}
+
+	tests := []struct {
+		name    string
+		value   any
+		want    any
+		wantErr string
+	}{
+		{name: "whole value keeps its type", value: "${steps.0.count}", want: float64(3)},
+		{name: "whole output", value: "${steps.0}", want: steps[0].Output},
+		{name: "array item", value: "${steps.0.keys.0.name}", want: "key1"},
+		{name: "embedded references are replaced with their text", value: "${steps.0.name}-${steps.0.count}", want: "st1-3"},
+		{name: "nested parameters", value: map[string]any{"tags": []any{"${steps.0.name}"}}, want: map[string]any{"tags": []any{"st1"}}},
+		{name: "missing key", value: "${steps.0.id}", wantErr: "the output of step 0 has no 'id'"},
+		{name: "missing item", value: "${steps.0.keys.1}", wantErr: "the output of step 0 has no item '1'"},
+		{name: "failed step", value: "${steps.1.name}", wantErr: "references step 1, which did not succeed"},
+		{name: "later step", value: "x-${steps.2.name}", wantErr: "references step 2, which did not succeed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveReferences(tt.value, steps)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveReferences(%v) error = %v, want %q", tt.value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveReferences(%v) error = %v", tt.value, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveReferences(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp.azure/internal/metadata"
+)
+
+// heartbeatInterval is how often progress is reported while a child command is running,
+// so clients with request timeouts that reset on progress do not give up on slow commands.
+const heartbeatInterval = 10 * time.Second
+
+// progressRelay reports the progress of a command call to the calling client, which asked
+// for it by sending a progress token. The child receives the same token and its progress
+// notifications are relayed upstream, interleaved with heartbeats while the call is pending.
+type progressRelay struct {
+	ctx     context.Context
+	server  *server.MCPServer
+	token   mcp.ProgressToken
+	started time.Time
+
+	// progress must increase with every notification, whichever side it came from.
+	mu       sync.Mutex
+	progress float64
+}
+
+// newProgressRelay returns nil when the caller did not ask for progress.
+func newProgressRelay(ctx context.Context, request mcp.CallToolRequest) *progressRelay {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	s := server.ServerFromContext(ctx)
+	if s == nil {
+		return nil
+	}
+
+	return &progressRelay{
+		ctx:     ctx,
+		server:  s,
+		token:   request.Params.Meta.ProgressToken,
+		started: time.Now(),
+	}
+}
+
+// notify sends a progress notification with the message to the caller.
+func (r *progressRelay) notify(message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.progress++
+	_ = r.server.SendNotificationToClient(r.ctx, metadata.MethodNotificationProgress, map[string]any{
+		"progressToken": r.token,
+		"progress":      r.progress,
+		"message":       message,
+	})
+}
+
+// relay forwards a progress notification of the child.
+// Its progress is kept in the message, the caller sees the relay's own progress count.
+func (r *progressRelay) relay(params mcp.ProgressNotificationParams) {
+	progress := fmt.Sprintf("%g", params.Progress)
+	if params.Total > 0 {
+		progress = fmt.Sprintf("%g/%g", params.Progress, params.Total)
+	}
+
+	if params.Message == "" {
+		r.notify(progress)
+		return
+	}
+	r.notify(fmt.Sprintf("%s (%s)", params.Message, progress))
+}
+
+// heartbeat reports that the command is still running every heartbeatInterval until stopped.
+func (r *progressRelay) heartbeat(toolName string, commandName string) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-r.ctx.Done():
+				return
+			case <-ticker.C:
+				r.notify(fmt.Sprintf("Waiting for tool %s, command %s (%s elapsed)",
+					toolName, commandName, time.Since(r.started).Round(time.Second)))
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// workflow is a prompt of the root server that guides an agent through a common Azure task
+// with the commands of the child tools.
+type workflow struct {
+	name        string
+	description string
+	arguments   []workflowArgument
+	// goal describes the task from the arguments of the prompt.
+	goal  func(args map[string]string) string
+	steps []workflowStep
+	// guidance is appended after the steps.
+	guidance string
+}
+
+type workflowArgument struct {
+	name        string
+	description string
+	required    bool
+}
+
+// workflowStep is a single step of a workflow. The command of the step is the best match for
+// the query among the known commands of the first registered tool of tools.
+type workflowStep struct {
+	task  string
+	tools []string
+	query string
+}
+
+var workflows = []workflow{
+	{
+		name:        "provision-azd-app",
+		description: "Create a new azd app, provision its Azure resources and deploy it.",
+		arguments: []workflowArgument{
+			{name: "name", description: "The name of the app and its azd environment.", required: true},
+			{name: "template", description: "The azd template to start from, such as todo-nodejs-mongo. Starts from the current directory when omitted."},
+			{name: "location", description: "The Azure region to provision in, such as eastus2."},
+			{name: "subscription", description: "The subscription to provision in, the default subscription when omitted."},
+		},
+		goal: func(args map[string]string) string {
+			goal := fmt.Sprintf("Provision a new azd app named %q", args["name"])
+			if args["template"] != "" {
+				goal += fmt.Sprintf(" from the template %q", args["template"])
+			}
+			if args["location"] != "" {
+				goal += " in " + args["location"]
+			}
+			if args["subscription"] != "" {
+				goal += fmt.Sprintf(", in the subscription %s", args["subscription"])
+			}
+			return goal + "."
+		},
+		steps: []workflowStep{
+			{task: "Initialize the project", tools: []string{"azd"}, query: "init project template"},
+			{task: "Create the azd environment with the name, location and subscription", tools: []string{"azd"}, query: "new environment"},
+			{task: "Provision the Azure resources of the app", tools: []string{"azd"}, query: "provision infrastructure resources"},
+			{task: "Deploy the app", tools: []string{"azd"}, query: "deploy app service"},
+			{task: "Show the endpoints of the deployed app", tools: []string{"azd"}, query: "show environment values endpoints"},
+		},
+		guidance: "Provisioning creates billable resources, run each step with \"dryRun\": true first and confirm the plan with the user.",
+	},
+	{
+		name:        "audit-role-assignments",
+		description: "Review the role assignments on a subscription, resource group or resource.",
+		arguments: []workflowArgument{
+			{name: "scope", description: "The scope to audit, a subscription, resource group or resource ID.", required: true},
+			{name: "principal", description: "Only audit the assignments of this user, group or service principal."},
+		},
+		goal: func(args map[string]string) string {
+			goal := fmt.Sprintf("Audit the role assignments on the scope %s", args["scope"])
+			if args["principal"] != "" {
+				goal += fmt.Sprintf(" for the principal %s", args["principal"])
+			}
+			return goal + "."
+		},
+		steps: []workflowStep{
+			{task: "List the role assignments on the scope, including those inherited from its parents", tools: []string{"role", "authorization"}, query: "list role assignments scope"},
+			{task: "List the role definitions the assignments refer to", tools: []string{"role", "authorization"}, query: "list role definitions"},
+			{task: "Look up the principals of the assignments", tools: []string{"entra", "graph"}, query: "get user group service principal"},
+		},
+		guidance: "Report privileged roles such as Owner, Contributor and User Access Administrator assigned directly to users, assignments to principals that no longer exist, and assignments broader than needed. Only read state, do not change any assignment.",
+	},
+	{
+		name:        "rotate-keyvault-secret",
+		description: "Rotate a Key Vault secret to a new version and retire the old one.",
+		arguments: []workflowArgument{
+			{name: "vault", description: "The name of the Key Vault.", required: true},
+			{name: "secret", description: "The name of the secret to rotate.", required: true},
+		},
+		goal: func(args map[string]string) string {
+			return fmt.Sprintf("Rotate the secret %q in the Key Vault %q.", args["secret"], args["vault"])
+		},
+		steps: []workflowStep{
+			{task: "Show the current version and attributes of the secret", tools: []string{"keyvault"}, query: "get secret"},
+			{task: "Set a new version of the secret", tools: []string{"keyvault"}, query: "set create secret value"},
+			{task: "Check that the new version is enabled and current", tools: []string{"keyvault"}, query: "get secret version"},
+			{task: "Disable the previous version once every app uses the new one", tools: []string{"keyvault"}, query: "update secret attributes enabled"},
+		},
+		guidance: "Never show or log secret values. Ask the user where the new value comes from, and run the write steps with \"dryRun\": true first.",
+	},
+	{
+		name:        "inventory-resource-group",
+		description: "List and summarize the resources of a resource group.",
+		arguments: []workflowArgument{
+			{name: "resourceGroup", description: "The name of the resource group.", required: true},
+			{name: "subscription", description: "The subscription of the resource group, the default subscription when omitted."},
+		},
+		goal: func(args map[string]string) string {
+			goal := fmt.Sprintf("Inventory the resource group %q", args["resourceGroup"])
+			if args["subscription"] != "" {
+				goal += fmt.Sprintf(" in the subscription %s", args["subscription"])
+			}
+			return goal + "."
+		},
+		steps: []workflowStep{
+			{task: "Show the resource group, its location and tags", tools: []string{"group", "resource"}, query: "show resource group"},
+			{task: "List the resources in the resource group", tools: []string{"group", "resource"}, query: "list resources group"},
+			{task: "Summarize the resources by type, with their locations and tags"},
+		},
+		guidance: "Only read state. Point out resources without tags, in unexpected locations, or that look unused.",
+	},
+}
+
+// Prompts exposes the workflow prompts of the root server. The prompts of the child tools
+// are proxied by the ChildProxy.
+type Prompts struct {
+	azure  *AzureTool
+	server *server.MCPServer
+}
+
+// NewPrompts creates the workflow prompts over the root tool.
+func NewPrompts(azure *AzureTool, s *server.MCPServer) *Prompts {
+	return &Prompts{
+		azure:  azure,
+		server: s,
+	}
+}
+
+// Register adds the workflow prompts to the server.
+func (p *Prompts) Register() {
+	serverPrompts := make([]server.ServerPrompt, 0, len(workflows))
+	for _, w := range workflows {
+		options := []mcp.PromptOption{mcp.WithPromptDescription(w.description)}
+		for _, arg := range w.arguments {
+			argOptions := []mcp.ArgumentOption{mcp.ArgumentDescription(arg.description)}
+			if arg.required {
+				argOptions = append(argOptions, mcp.RequiredArgument())
+			}
+			options = append(options, mcp.WithArgument(arg.name, argOptions...))
+		}
+
+		serverPrompts = append(serverPrompts, server.ServerPrompt{
+			Prompt:  mcp.NewPrompt(w.name, options...),
+			Handler: p.workflowHandler(w),
+		})
+	}
+
+	p.server.AddPrompts(serverPrompts...)
+}
+
+// workflowHandler expands a workflow into the steps to take, naming the tool and command of
+// each step when they are known.
+func (p *Prompts) workflowHandler(w workflow) server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		args := request.Params.Arguments
+		for _, arg := range w.arguments {
+			if arg.required && args[arg.name] == "" {
+				return nil, fmt.Errorf("missing required argument '%s'", arg.name)
+			}
+		}
+
+		var text strings.Builder
+		text.WriteString(w.goal(args) + "\n\n")
+		text.WriteString("Use the azure tool for every step, and check the result of each step before the next one:\n\n")
+		for i, step := range w.steps {
+			fmt.Fprintf(&text, "%d. %s", i+1, step.task)
+			if step.query != "" {
+				text.WriteString(": " + p.resolveStep(step))
+			}
+			text.WriteString(".\n")
+		}
+		text.WriteString("\n" + w.guidance + "\n")
+
+		return mcp.NewGetPromptResult(w.description, []mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text.String())),
+		}), nil
+	}
+}
+
+// resolveStep tells the agent how to run a step: with the best matching known command of the
+// tool of the step, or else how to find the command. No child server is started to expand a prompt.
+func (p *Prompts) resolveStep(step workflowStep) string {
+	toolName := ""
+	for _, name := range step.tools {
+		if _, ok := p.azure.toolMetadataMap[name]; ok {
+			toolName = name
+			break
+		}
+	}
+	if toolName == "" {
+		return fmt.Sprintf("call the azure tool with \"query\": %q to find the command", step.query)
+	}
+
+	idx, ok := p.azure.commandIndex(toolName)
+	if !ok {
+		return fmt.Sprintf("call the azure tool with \"learn\": true and tool %q to find the command", toolName)
+	}
+
+	if results := idx.search(step.query, 1); len(results) > 0 {
+		return fmt.Sprintf("call the azure tool with tool %q and command %q", toolName, results[0].Command.Name)
+	}
+	return fmt.Sprintf("call the azure tool with \"query\": %q and tool %q to find the command", step.query, toolName)
+}
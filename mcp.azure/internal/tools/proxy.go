@@ -0,0 +1,320 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp.azure/internal/metadata"
+)
+
+// childResourceTemplate is the URI template of the resources of child tools, the URI of the
+// child resource is escaped into a single path segment.
+const childResourceTemplate = "azure://tools/{tool}/resources/{uri}"
+
+// childListTimeout bounds listing the prompts and resources of a child tool.
+const childListTimeout = 30 * time.Second
+
+// ChildProxy aggregates the resources and prompts of the child tools, namespaced by tool as
+// "azure://tools/<tool>/resources/<uri>" and "<tool>.<prompt>". Reading a resource or getting
+// a prompt is forwarded to the child client of the calling session.
+//
+// Children are not started to be listed: the prompts and resources of a child are registered
+// from the schema cache, or once the child has been started for any other reason, and are
+// listed again whenever the child notifies that they changed. Clients are notified that the
+// lists changed each time prompts or resources are added or removed.
+type ChildProxy struct {
+	azure         *AzureTool
+	server        *server.MCPServer
+	subscriptions *Subscriptions
+
+	mu sync.Mutex
+	// catalogs holds the prompts and resources registered for each child tool.
+	catalogs map[string]metadata.Catalog
+	// listed holds the child tools listed since the server started.
+	listed map[string]bool
+	// listing holds the child tools being listed after they were started.
+	listing map[string]bool
+}
+
+// NewChildProxy creates the proxy over the child tools of the root tool.
+func NewChildProxy(azure *AzureTool, s *server.MCPServer, subscriptions *Subscriptions) *ChildProxy {
+	return &ChildProxy{
+		azure:         azure,
+		server:        s,
+		subscriptions: subscriptions,
+		catalogs:      make(map[string]metadata.Catalog),
+		listed:        make(map[string]bool),
+		listing:       make(map[string]bool),
+	}
+}
+
+// Register adds the template through which any resource of a child tool can be read, and the
+// prompts and resources of every child tool whose catalog is cached, without starting any child.
+func (p *ChildProxy) Register() {
+	p.server.AddResourceTemplate(
+		mcp.NewResourceTemplate(childResourceTemplate, "Azure tool resource",
+			mcp.WithTemplateDescription("A resource of a child tool, with the URI of the resource escaped as a single path segment."),
+		),
+		p.readTemplate,
+	)
+
+	for _, t := range p.azure.childTools {
+		tm := p.azure.toolMetadataMap[t.Name]
+		if catalog, ok := p.azure.schemas.Catalog(tm); ok {
+			p.update(tm, catalog)
+		}
+	}
+}
+
+// ChildStarted lists the prompts and resources of a child tool the first time it is started.
+// A child that fails to list them is listed again the next time it is started.
+func (p *ChildProxy) ChildStarted(sessionID string, name string, c *client.Client) {
+	tm, ok := p.azure.toolMetadataMap[name]
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	if p.listed[name] || p.listing[name] {
+		p.mu.Unlock()
+		return
+	}
+	p.listing[name] = true
+	p.mu.Unlock()
+
+	// The child is listed while it serves the call that started it.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), childListTimeout)
+		defer cancel()
+
+		err := p.refresh(ctx, tm, c)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		delete(p.listing, name)
+		if err == nil {
+			p.listed[name] = true
+		}
+	}()
+}
+
+// ChildNotification lists the prompts and resources of a child tool again when they changed,
+// and forwards updates of its resources to the session of the child when it subscribed to them.
+func (p *ChildProxy) ChildNotification(sessionID string, name string, notification mcp.JSONRPCNotification) {
+	tm, ok := p.azure.toolMetadataMap[name]
+	if !ok {
+		return
+	}
+
+	switch notification.Method {
+	case mcp.MethodNotificationResourcesListChanged, mcp.MethodNotificationPromptsListChanged:
+		// Notifications are handled while the child transport waits, so the child is listed
+		// after the handler returned.
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), childListTimeout)
+			defer cancel()
+
+			clients, err := p.azure.clients.Get(sessionID)
+			if err != nil {
+				return
+			}
+			_ = clients.Call(ctx, tm, func(ctx context.Context, c *client.Client) error {
+				return p.refresh(ctx, tm, c)
+			})
+		}()
+	case mcp.MethodNotificationResourceUpdated:
+		uri, _ := notification.Params.AdditionalFields["uri"].(string)
+		if uri == "" {
+			return
+		}
+		p.subscriptions.notifySession(sessionID, childResourceURI(name, uri))
+	}
+}
+
+// refresh lists the prompts and resources of a child, caches them and registers any changes.
+// A child that fails to list them keeps what was registered before.
+func (p *ChildProxy) refresh(ctx context.Context, tm metadata.ToolMetadata, c *client.Client) error {
+	catalog := metadata.Catalog{Prompts: []mcp.Prompt{}, Resources: []mcp.Resource{}}
+	capabilities := c.GetServerCapabilities()
+
+	if capabilities.Prompts != nil {
+		result, err := c.ListPrompts(ctx, mcp.ListPromptsRequest{})
+		if err != nil {
+			return fmt.Errorf("failed to list prompts of tool %s: %w", tm.Metadata().Name, err)
+		}
+		catalog.Prompts = result.Prompts
+	}
+	if capabilities.Resources != nil {
+		result, err := c.ListResources(ctx, mcp.ListResourcesRequest{})
+		if err != nil {
+			return fmt.Errorf("failed to list resources of tool %s: %w", tm.Metadata().Name, err)
+		}
+		catalog.Resources = result.Resources
+	}
+
+	// A cache that cannot be written only costs listing the child again after a restart.
+	_ = p.azure.schemas.SetCatalog(tm, catalog)
+	p.update(tm, catalog)
+	return nil
+}
+
+// update replaces the prompts and resources registered for a child tool when they changed.
+// Only prompts and resources the child no longer has are removed, the others are replaced.
+func (p *ChildProxy) update(tm metadata.ToolMetadata, catalog metadata.Catalog) {
+	toolName := tm.Metadata().Name
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	previous, registered := p.catalogs[toolName]
+	p.catalogs[toolName] = catalog
+
+	if !registered || !sameJson(previous.Resources, catalog.Resources) {
+		current := make(map[string]bool, len(catalog.Resources))
+		serverResources := make([]server.ServerResource, 0, len(catalog.Resources))
+		for _, r := range catalog.Resources {
+			childResource := r
+			childResource.URI = childResourceURI(toolName, r.URI)
+			current[childResource.URI] = true
+
+			serverResources = append(serverResources, server.ServerResource{
+				Resource: childResource,
+				Handler: func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+					return p.read(ctx, tm, r.URI)
+				},
+			})
+		}
+
+		var removed []string
+		for _, r := range previous.Resources {
+			if uri := childResourceURI(toolName, r.URI); !current[uri] {
+				removed = append(removed, uri)
+			}
+		}
+		if len(removed) > 0 {
+			p.server.DeleteResources(removed...)
+		}
+		if len(serverResources) > 0 {
+			p.server.AddResources(serverResources...)
+		}
+	}
+
+	if !registered || !sameJson(previous.Prompts, catalog.Prompts) {
+		current := make(map[string]bool, len(catalog.Prompts))
+		serverPrompts := make([]server.ServerPrompt, 0, len(catalog.Prompts))
+		for _, prompt := range catalog.Prompts {
+			childPrompt := prompt
+			childPrompt.Name = flatToolName(toolName, prompt.Name)
+			current[childPrompt.Name] = true
+
+			serverPrompts = append(serverPrompts, server.ServerPrompt{
+				Prompt:  childPrompt,
+				Handler: p.promptHandler(tm, prompt.Name),
+			})
+		}
+
+		var removed []string
+		for _, prompt := range previous.Prompts {
+			if name := flatToolName(toolName, prompt.Name); !current[name] {
+				removed = append(removed, name)
+			}
+		}
+		if len(removed) > 0 {
+			p.server.DeletePrompts(removed...)
+		}
+		if len(serverPrompts) > 0 {
+			p.server.AddPrompts(serverPrompts...)
+		}
+	}
+}
+
+// readTemplate reads any resource of a child tool, including those of its resource templates.
+func (p *ChildProxy) readTemplate(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	toolName := templateArgument(request, "tool")
+	tm, ok := p.azure.toolMetadataMap[toolName]
+	if !ok {
+		return nil, fmt.Errorf("tool %s not found", toolName)
+	}
+
+	uri, err := url.PathUnescape(templateArgument(request, "uri"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource URI: %w", err)
+	}
+
+	return p.read(ctx, tm, uri)
+}
+
+// read reads a resource from the child client of the calling session, and namespaces the URIs
+// of its contents.
+func (p *ChildProxy) read(ctx context.Context, tm metadata.ToolMetadata, uri string) ([]mcp.ResourceContents, error) {
+	toolName := tm.Metadata().Name
+
+	var result *mcp.ReadResourceResult
+	err := p.azure.call(ctx, tm, func(ctx context.Context, c *client.Client) error {
+		var err error
+		result, err = c.ReadResource(ctx, mcp.ReadResourceRequest{
+			Params: mcp.ReadResourceParams{URI: uri},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource %s of tool %s: %w", uri, toolName, err)
+	}
+
+	contents := make([]mcp.ResourceContents, 0, len(result.Contents))
+	for _, content := range result.Contents {
+		switch c := content.(type) {
+		case mcp.TextResourceContents:
+			c.URI = childResourceURI(toolName, c.URI)
+			content = c
+		case mcp.BlobResourceContents:
+			c.URI = childResourceURI(toolName, c.URI)
+			content = c
+		}
+		contents = append(contents, content)
+	}
+
+	return contents, nil
+}
+
+// promptHandler gets a prompt from the child client of the calling session.
+func (p *ChildProxy) promptHandler(tm metadata.ToolMetadata, name string) server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		var result *mcp.GetPromptResult
+		err := p.azure.call(ctx, tm, func(ctx context.Context, c *client.Client) error {
+			var err error
+			result, err = c.GetPrompt(ctx, mcp.GetPromptRequest{
+				Params: mcp.GetPromptParams{Name: name, Arguments: request.Params.Arguments},
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get prompt %s of tool %s: %w", name, tm.Metadata().Name, err)
+		}
+
+		return result, nil
+	}
+}
+
+// childResourceURI namespaces the URI of a resource of a child tool.
+func childResourceURI(toolName string, uri string) string {
+	// Colons are escaped too, so the URI matches the template as a single path segment.
+	escaped := strings.ReplaceAll(url.PathEscape(uri), ":", "%3A")
+	return strings.NewReplacer("{tool}", toolName, "{uri}", escaped).Replace(childResourceTemplate)
+}
+
+func sameJson(a any, b any) bool {
+	rawA, errA := json.Marshal(a)
+	rawB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(rawA) == string(rawB)
+}
@@ -0,0 +1,269 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp.azure/internal/metadata"
+)
+
+// URIs of the resources of the root server.
+const (
+	accountResourceURI       = "azure://context/account"
+	subscriptionsResourceURI = "azure://context/subscriptions"
+	extensionsResourceURI    = "azure://extensions"
+	schemaResourceTemplate   = "azure://tools/{tool}/schema"
+)
+
+// resourceWatchInterval is how often the Azure CLI profile and the extensions are checked for changes.
+const resourceWatchInterval = 5 * time.Second
+
+// Resources exposes the Azure context of the root server as MCP resources: the signed in
+// account and its subscriptions, the child tools and their extensions, and the commands of
+// every child tool. Agents can read them without spending a tool call.
+type Resources struct {
+	azure         *AzureTool
+	server        *server.MCPServer
+	subscriptions *Subscriptions
+}
+
+// toolInfo describes a child tool in the extensions resource.
+type toolInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Source      string `json:"source"`
+	// Extension is the version of the azd extension, unset for tools registered in mcp.json.
+	Extension *metadata.ExtensionVersion `json:"extension,omitempty"`
+	// Schema is the URI of the resource with the commands of the tool.
+	Schema string `json:"schema"`
+}
+
+// toolSchema is the content of the schema resource of a child tool.
+type toolSchema struct {
+	Tool     string     `json:"tool"`
+	Commands []mcp.Tool `json:"commands"`
+}
+
+// NewResources creates the resources over the root tool. Updates are sent to the sessions
+// subscribed to them.
+func NewResources(azure *AzureTool, s *server.MCPServer, subscriptions *Subscriptions) *Resources {
+	return &Resources{
+		azure:         azure,
+		server:        s,
+		subscriptions: subscriptions,
+	}
+}
+
+// Register adds the resources and the schema resource template to the server.
+func (r *Resources) Register() {
+	r.server.AddResources(
+		server.ServerResource{
+			Resource: mcp.NewResource(accountResourceURI, "Azure account",
+				mcp.WithResourceDescription("The account the Azure CLI is signed in as, its tenant and default subscription."),
+				mcp.WithMIMEType("application/json"),
+			),
+			Handler: r.readAccount,
+		},
+		server.ServerResource{
+			Resource: mcp.NewResource(subscriptionsResourceURI, "Azure subscriptions",
+				mcp.WithResourceDescription("The subscriptions the Azure CLI is signed in to, with the default subscription marked."),
+				mcp.WithMIMEType("application/json"),
+			),
+			Handler: r.readSubscriptions,
+		},
+		server.ServerResource{
+			Resource: mcp.NewResource(extensionsResourceURI, "Azure tools",
+				mcp.WithResourceDescription("The child tools of the azure tool, where they are registered and the versions of their azd extensions."),
+				mcp.WithMIMEType("application/json"),
+			),
+			Handler: r.readExtensions,
+		},
+	)
+
+	r.server.AddResourceTemplate(
+		mcp.NewResourceTemplate(schemaResourceTemplate, "Azure tool schema",
+			mcp.WithTemplateDescription("The commands of a child tool and their input schemas, as listed by the \"learn\" parameter of the azure tool."),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		r.readSchema,
+	)
+}
+
+func (r *Resources) readAccount(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	account, err := metadata.LoadAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonContents(request.Params.URI, account)
+}
+
+func (r *Resources) readSubscriptions(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	subscriptions, err := metadata.LoadSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonContents(request.Params.URI, subscriptions)
+}
+
+func (r *Resources) readExtensions(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	infos := make([]toolInfo, 0, len(r.azure.childTools))
+	for _, t := range r.azure.childTools {
+		tm := r.azure.toolMetadataMap[t.Name]
+		info := toolInfo{
+			Name:        t.Name,
+			Description: t.Description,
+			Source:      tm.Source(),
+			Schema:      schemaResourceURI(t.Name),
+		}
+		if azdTool, ok := tm.(*metadata.AzdToolMetadata); ok {
+			version := azdTool.VersionInfo()
+			info.Extension = &version
+		}
+		infos = append(infos, info)
+	}
+
+	return jsonContents(request.Params.URI, infos)
+}
+
+// readSchema lists the commands of a child tool through the schema cache, or else the child
+// client of the calling session, the same way the "learn" parameter does.
+func (r *Resources) readSchema(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	toolName := templateArgument(request, "tool")
+	tm, ok := r.azure.toolMetadataMap[toolName]
+	if !ok {
+		return nil, fmt.Errorf("tool %s not found", toolName)
+	}
+
+	commands, err := r.azure.listCommands(ctx, tm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commands of tool %s: %w", toolName, err)
+	}
+
+	return jsonContents(request.Params.URI, toolSchema{Tool: toolName, Commands: commands})
+}
+
+// Watch notifies the subscribed sessions that a resource was updated until the context is done:
+// the account and subscriptions when the profile of the Azure CLI changes, such as after
+// "az account set", and the extensions and the schema of a tool when an extension is installed,
+// upgraded or removed, by the server or with azd.
+func (r *Resources) Watch(ctx context.Context) {
+	ticker := time.NewTicker(resourceWatchInterval)
+	defer ticker.Stop()
+
+	defaultSubscription, subscriptions := profileState()
+	versions, _ := metadata.InstalledExtensions(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		nextDefault, nextSubscriptions := profileState()
+		if nextDefault != defaultSubscription {
+			r.notifyUpdated(accountResourceURI)
+		}
+		if nextSubscriptions != subscriptions {
+			r.notifyUpdated(subscriptionsResourceURI)
+		}
+		defaultSubscription, subscriptions = nextDefault, nextSubscriptions
+
+		// Extensions that cannot be listed are checked again on the next tick.
+		nextVersions, err := metadata.InstalledExtensions(ctx)
+		if err != nil || versions == nil {
+			versions = nextVersions
+			continue
+		}
+		if r.extensionsChanged(versions, nextVersions) {
+			r.notifyUpdated(extensionsResourceURI)
+		}
+		versions = nextVersions
+	}
+}
+
+// extensionsChanged reports whether an extension was installed, upgraded, removed or added
+// between two listings. The tools of changed extensions are updated, and the sessions subscribed
+// to their schemas notified.
+func (r *Resources) extensionsChanged(versions map[string]string, nextVersions map[string]string) bool {
+	changed := len(versions) != len(nextVersions)
+	for id, version := range nextVersions {
+		previous, ok := versions[id]
+		if ok && previous == version {
+			continue
+		}
+		changed = true
+
+		for name, tm := range r.azure.toolMetadataMap {
+			if azdTool, isAzd := tm.(*metadata.AzdToolMetadata); isAzd && azdTool.VersionInfo().ID == id {
+				azdTool.UpdateInstalled(version)
+				r.notifyUpdated(schemaResourceURI(name))
+			}
+		}
+	}
+
+	return changed
+}
+
+func (r *Resources) notifyUpdated(uri string) {
+	r.subscriptions.notifyUpdated(uri)
+}
+
+// profileState returns the default subscription and the subscriptions in the profile of the
+// Azure CLI, both empty when there is no profile.
+func profileState() (string, string) {
+	subscriptions, err := metadata.ProfileSubscriptions()
+	if err != nil {
+		return "", ""
+	}
+
+	defaultSubscription := ""
+	for _, s := range subscriptions {
+		if s.IsDefault {
+			defaultSubscription = s.ID
+		}
+	}
+	raw, _ := json.Marshal(subscriptions)
+
+	return defaultSubscription, string(raw)
+}
+
+func schemaResourceURI(toolName string) string {
+	return strings.Replace(schemaResourceTemplate, "{tool}", toolName, 1)
+}
+
+// templateArgument returns a variable of the URI template a resource was read through.
+func templateArgument(request mcp.ReadResourceRequest, name string) string {
+	switch v := request.Params.Arguments[name].(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+
+	return ""
+}
+
+func jsonContents(uri string, v any) ([]mcp.ResourceContents, error) {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource %s: %w", uri, err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: "application/json",
+			Text:     string(raw),
+		},
+	}, nil
+}
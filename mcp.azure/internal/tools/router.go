@@ -0,0 +1,288 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// maxCandidates limits how many tools or commands are suggested when an intent is ambiguous.
+	maxCandidates = 5
+	// samplingMaxTokens bounds the completions requested from the client's model.
+	samplingMaxTokens = 1024
+)
+
+// toolCallSchemaJson is the result schema the client's model must follow when it picks a command.
+const toolCallSchemaJson = `{
+  "type": "object",
+  "properties": {
+    "tool": {
+      "type": "string",
+      "description": "The name of the command to call, or \"Unknown\" if no command matches."
+    },
+    "parameters": {
+      "type": "object",
+      "description": "The parameters to call the command with."
+    }
+  },
+  "required": ["tool", "parameters"]
+}`
+
+// sampledCommand is the command chosen by the client's model.
+type sampledCommand struct {
+	Tool       string         `json:"tool"`
+	Parameters map[string]any `json:"parameters"`
+}
+
+// routeIntent handles a call that carries an intent but no command, and optionally a tool.
+// When the client supports sampling its model picks the tool, command and parameters.
+// Otherwise the intent is ranked locally against the tool and command descriptions: a
+// single read-only best match with valid parameters is dispatched, and anything less
+// certain is narrowed down to the best candidates for the agent to choose from.
+func (a *AzureTool) routeIntent(ctx context.Context, request mcp.CallToolRequest, intent string, toolName string) (*mcp.CallToolResult, error) {
+	params := request.GetArguments()["parameters"]
+	sampling := supportsSampling(ctx)
+
+	if toolName == "" && sampling {
+		toolName = a.sampleTool(ctx, intent)
+	}
+	if toolName == "" {
+		candidates := a.rankTools(intent)
+		if len(candidates) == 0 {
+			return noRouteResult(intent), nil
+		}
+		if len(candidates) > 1 && candidates[1].score == candidates[0].score {
+			return toolCandidatesResult(intent, candidates), nil
+		}
+		toolName = candidates[0].tool.Name
+	}
+
+	tm, ok := a.toolMetadataMap[toolName]
+	if !ok {
+		return toolNotFoundResult(toolName), nil
+	}
+
+	commands, err := a.listCommands(ctx, tm)
+	if err != nil {
+		return a.learnTool(ctx, toolName)
+	}
+
+	if sampling {
+		if command, ok := a.sampleCommand(ctx, intent, toolName, commands, params); ok {
+			return a.dispatch(ctx, tm, newDispatchOptions(request), commandRequest(request, command.Tool, command.Parameters)), nil
+		}
+	}
+
+	var candidates []searchResult
+	if idx, ok := a.commandIndex(toolName); ok {
+		candidates = idx.search(intent, maxCandidates)
+	}
+	if len(candidates) == 0 {
+		return a.learnTool(ctx, toolName)
+	}
+
+	best := candidates[0]
+	unique := len(candidates) == 1 || candidates[1].Score < best.Score
+	opts := newDispatchOptions(request)
+	// The parameters are checked with the subscription and tenant the command would get.
+	withContext, _, unsupported := a.withContext(ctx, opts, best.Command, params)
+	if unique && isIdempotent(best.Command) && len(unsupported) == 0 && len(validateParameters(inputSchema(best.Command), withContext, false)) == 0 {
+		return a.dispatch(ctx, tm, opts, commandRequest(request, best.Command.Name, params)), nil
+	}
+
+	return commandCandidatesResult(intent, toolName, candidates)
+}
+
+// sampleTool asks the client's model for the tool that best matches the intent.
+// An empty name is returned when the model does not pick a known tool.
+func (a *AzureTool) sampleTool(ctx context.Context, intent string) string {
+	toolsJson, err := json.MarshalIndent(a.childTools, "", "  ")
+	if err != nil {
+		return ""
+	}
+
+	text, err := sample(ctx, fmt.Sprintf(`
+		The following is a list of available tools for the Azure server.
+
+		Your task:
+		- Select a single tool that best matches the user's intent and return the name of the tool.
+		- Only return tool names that are defined in the provided list.
+		- If no tool matches, return "Unknown".
+
+		Intent:
+		%s
+
+		Available Tools:
+		%s
+	`, intent, toolsJson))
+	if err != nil {
+		return ""
+	}
+
+	toolName := strings.Trim(strings.TrimSpace(text), "\"'`")
+	if _, ok := a.toolMetadataMap[toolName]; !ok {
+		return ""
+	}
+
+	return toolName
+}
+
+// sampleCommand asks the client's model for the command and parameters that match the intent.
+func (a *AzureTool) sampleCommand(
+	ctx context.Context,
+	intent string,
+	toolName string,
+	commands []mcp.Tool,
+	params any,
+) (sampledCommand, bool) {
+	commandsJson, err := json.MarshalIndent(commands, "", "  ")
+	if err != nil {
+		return sampledCommand{}, false
+	}
+	paramsJson, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return sampledCommand{}, false
+	}
+
+	text, err := sample(ctx, fmt.Sprintf(`
+		This is a list of available commands for the %s server.
+
+		Your task:
+		- Select the single command that best matches the user's intent.
+		- Return a valid JSON object that matches the provided result schema.
+		- Map the user's intent and known parameters to the command's input schema, ensuring parameter names and types match the schema exactly (no extra or missing parameters).
+		- Only include parameters that are defined in the selected command's input schema.
+		- Do not guess or invent parameters.
+		- If no command matches, return JSON schema with "Unknown" tool name.
+
+		Result Schema:
+		%s
+
+		Intent:
+		%s
+
+		Known Parameters:
+		%s
+
+		Available Commands:
+		%s
+	`, toolName, toolCallSchemaJson, intent, paramsJson, commandsJson))
+	if err != nil {
+		return sampledCommand{}, false
+	}
+
+	// Models often wrap JSON in a markdown code fence.
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.Trim(text, "`\n ")
+
+	var command sampledCommand
+	if err := json.Unmarshal([]byte(text), &command); err != nil {
+		return sampledCommand{}, false
+	}
+	if _, ok := findCommand(commands, command.Tool); !ok {
+		return sampledCommand{}, false
+	}
+
+	return command, true
+}
+
+// supportsSampling reports whether the calling client advertised the sampling capability.
+func supportsSampling(ctx context.Context) bool {
+	session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo)
+	if !ok {
+		return false
+	}
+	if _, ok := session.(server.SessionWithSampling); !ok {
+		return false
+	}
+
+	return session.GetClientCapabilities().Sampling != nil
+}
+
+// sample requests a completion for the prompt from the client's model.
+func sample(ctx context.Context, prompt string) (string, error) {
+	s := server.ServerFromContext(ctx)
+	if s == nil {
+		return "", fmt.Errorf("no server in context")
+	}
+
+	result, err := s.RequestSampling(ctx, mcp.CreateMessageRequest{
+		CreateMessageParams: mcp.CreateMessageParams{
+			Messages: []mcp.SamplingMessage{
+				{
+					Role:    mcp.RoleUser,
+					Content: mcp.NewTextContent(prompt),
+				},
+			},
+			MaxTokens: samplingMaxTokens,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return mcp.GetTextFromContent(result.Content), nil
+}
+
+// commandRequest returns a copy of the root request addressed to a child command.
+// The progress token in its metadata is kept, the headers of the root request are not.
+func commandRequest(request mcp.CallToolRequest, commandName string, params any) mcp.CallToolRequest {
+	childRequest := request
+	childRequest.Header = nil
+	childRequest.Params.Name = commandName
+	childRequest.Params.Arguments = params
+	return childRequest
+}
+
+func noRouteResult(intent string) *mcp.CallToolResult {
+	return errorResult(toolError{
+		Code:       codeToolNotFound,
+		Message:    "no tool matches the intent: " + intent,
+		NextAction: `Run again with the "learn" argument to get a list of available tools and their parameters.`,
+	}, fmt.Sprintf(`
+		No tool matches the intent: %s
+		Run again with the "learn" argument to get a list of available tools and their parameters.
+	`, intent))
+}
+
+func toolCandidatesResult(intent string, candidates []toolMatch) *mcp.CallToolResult {
+	var text strings.Builder
+	fmt.Fprintf(&text, "Several tools match the intent: %s\n", intent)
+	for i, c := range candidates {
+		if i == maxCandidates {
+			break
+		}
+		fmt.Fprintf(&text, "- %s: %s\n", c.tool.Name, strings.TrimSpace(c.tool.Description))
+	}
+	text.WriteString(`
+Run again with the "learn" argument and the "tool" name to get the commands and their parameters,
+or with the "tool" argument and the same "intent" to pick a command of that tool.
+`)
+
+	return mcp.NewToolResultText(text.String())
+}
+
+func commandCandidatesResult(intent string, toolName string, candidates []searchResult) (*mcp.CallToolResult, error) {
+	commands := make([]mcp.Tool, 0, len(candidates))
+	for _, c := range candidates {
+		commands = append(commands, c.Command)
+	}
+
+	commandsJson, err := json.MarshalIndent(mcp.ListToolsResult{Tools: commands}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed get get learn content: %w", err)
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf(`
+		Here are the commands of the '%s' tool that best match the intent: %s
+		Identify the command you want to execute and run again with the "tool", "command", and "parameters" arguments.
+
+		%s
+	`, toolName, intent, commandsJson)), nil
+}
@@ -0,0 +1,307 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxSearchResults is the number of commands returned for a "query".
+const maxSearchResults = 10
+
+// Field weights of the search index, a term matching a command name says more about
+// the command than one matching a parameter name, which says more than its description.
+const (
+	commandNameWeight = 3.0
+	parameterWeight   = 2.0
+	descriptionWeight = 1.0
+)
+
+// stopWords are ignored when scoring, they carry no meaning about which command to pick.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "for": true, "from": true, "in": true,
+	"into": true, "is": true, "it": true, "me": true, "my": true, "of": true, "on": true,
+	"or": true, "please": true, "the": true, "this": true, "to": true, "with": true,
+	"all": true, "i": true, "want": true, "need": true, "can": true, "you": true,
+}
+
+// tokenize splits text into lower case terms, dropping stop words and plural suffixes
+// so "Lists the storage accounts", "list-storage-account" and "storageAccount" share their terms.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(splitCamelCase(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.ToLower(field)
+		if stopWords[field] {
+			continue
+		}
+		terms = append(terms, stem(field))
+	}
+
+	return terms
+}
+
+// splitCamelCase inserts a space before every upper case letter that follows a lower case letter or digit.
+func splitCamelCase(text string) string {
+	var b strings.Builder
+	var prev rune
+	for _, r := range text {
+		if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)) {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
+
+func stem(term string) string {
+	switch {
+	case len(term) > 4 && strings.HasSuffix(term, "ies"):
+		return term[:len(term)-3] + "y"
+	case len(term) > 3 && strings.HasSuffix(term, "s") && !strings.HasSuffix(term, "ss"):
+		return term[:len(term)-1]
+	default:
+		return term
+	}
+}
+
+func termSet(terms []string) map[string]bool {
+	set := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		set[term] = true
+	}
+	return set
+}
+
+// searchResult is a single command matching a "query".
+type searchResult struct {
+	Tool    string   `json:"tool"`
+	Command mcp.Tool `json:"command"`
+	Score   float64  `json:"score"`
+}
+
+// searchDocument holds the weighted terms of a single child command.
+type searchDocument struct {
+	tool    string
+	command mcp.Tool
+	terms   map[string]float64
+}
+
+// searchIndex is a small in-memory text index over child commands.
+// Documents are scored by the field weight of each matching query term,
+// scaled by how rare the term is across all commands.
+type searchIndex struct {
+	documents []searchDocument
+	// frequency counts the documents each term appears in.
+	frequency map[string]int
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{frequency: make(map[string]int)}
+}
+
+// add indexes the name, description and parameter names of a command.
+func (idx *searchIndex) add(toolName string, command mcp.Tool) {
+	terms := make(map[string]float64)
+	addTerms := func(text string, weight float64) {
+		for _, term := range tokenize(text) {
+			terms[term] = max(terms[term], weight)
+		}
+	}
+
+	addTerms(command.Description, descriptionWeight)
+	if properties, ok := inputSchema(command)["properties"].(map[string]any); ok {
+		for name, property := range properties {
+			addTerms(name, parameterWeight)
+			if p, ok := property.(map[string]any); ok {
+				if description, ok := p["description"].(string); ok {
+					addTerms(description, descriptionWeight)
+				}
+			}
+		}
+	}
+	addTerms(command.Name, commandNameWeight)
+
+	for term := range terms {
+		idx.frequency[term]++
+	}
+	idx.documents = append(idx.documents, searchDocument{tool: toolName, command: command, terms: terms})
+}
+
+// search returns the best matching commands for the query, best match first.
+// A limit of zero returns every match.
+func (idx *searchIndex) search(query string, limit int) []searchResult {
+	queryTerms := termSet(tokenize(query))
+
+	var results []searchResult
+	for _, doc := range idx.documents {
+		score := 0.0
+		for term := range queryTerms {
+			if weight, ok := doc.terms[term]; ok {
+				score += weight * idx.idf(term)
+			}
+		}
+		if score > 0 {
+			results = append(results, searchResult{
+				Tool:    doc.tool,
+				Command: doc.command,
+				Score:   math.Round(score*100) / 100,
+			})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// idf weighs rare terms above terms shared by many commands, such as "azure" or "list".
+func (idx *searchIndex) idf(term string) float64 {
+	n := float64(len(idx.documents))
+	return math.Log(1 + (n-float64(idx.frequency[term])+0.5)/(float64(idx.frequency[term])+0.5))
+}
+
+// addTool indexes the name and description of a child tool, as a document whose command is the tool itself.
+func (idx *searchIndex) addTool(tool mcp.Tool) {
+	idx.add(tool.Name, mcp.Tool{Name: tool.Name, Description: tool.Description})
+}
+
+// commandIndex returns the search index over the known commands of a child tool. It is built
+// once and rebuilt only after the commands of the tool are listed again.
+func (a *AzureTool) commandIndex(toolName string) (*searchIndex, bool) {
+	a.listedMu.Lock()
+	idx, ok := a.indexes[toolName]
+	a.listedMu.Unlock()
+	if ok {
+		return idx, true
+	}
+
+	commands, ok := a.knownCommands(toolName)
+	if !ok {
+		return nil, false
+	}
+
+	idx = newSearchIndex()
+	for _, command := range commands {
+		idx.add(toolName, command)
+	}
+
+	a.listedMu.Lock()
+	a.indexes[toolName] = idx
+	a.listedMu.Unlock()
+
+	return idx, true
+}
+
+// toolMatch is a child tool matching an intent.
+type toolMatch struct {
+	tool  mcp.Tool
+	score float64
+}
+
+// rankTools scores every child tool by its own name and description and, when its commands
+// are known, by its best matching command so tools with terse descriptions can still be found.
+// Tools that match at all are returned, best match first.
+func (a *AzureTool) rankTools(intent string) []toolMatch {
+	scores := make(map[string]float64)
+	for _, result := range a.toolIndex.search(intent, 0) {
+		scores[result.Tool] += result.Score
+	}
+	for _, t := range a.childTools {
+		if idx, ok := a.commandIndex(t.Name); ok {
+			if best := idx.search(intent, 1); len(best) > 0 {
+				scores[t.Name] += best[0].Score
+			}
+		}
+	}
+
+	var matches []toolMatch
+	for _, t := range a.childTools {
+		if score := scores[t.Name]; score > 0 {
+			matches = append(matches, toolMatch{tool: t, score: math.Round(score*100) / 100})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	return matches
+}
+
+// searchCommands searches the known commands of every child tool, or of a single tool.
+// Only cached schemas and commands listed since the server started are searched, so
+// no child server is started to answer a query.
+func (a *AzureTool) searchCommands(query string, toolName string) (*mcp.CallToolResult, error) {
+	if toolName != "" {
+		if _, ok := a.toolMetadataMap[toolName]; !ok {
+			return toolNotFoundResult(toolName), nil
+		}
+	}
+
+	idx := newSearchIndex()
+	var unknown []string
+	for _, t := range a.childTools {
+		if toolName != "" && t.Name != toolName {
+			continue
+		}
+
+		commands, ok := a.knownCommands(t.Name)
+		if !ok {
+			unknown = append(unknown, t.Name)
+			continue
+		}
+		for _, command := range commands {
+			idx.add(t.Name, command)
+		}
+	}
+
+	results := idx.search(query, maxSearchResults)
+	resultsJson, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed get get learn content: %w", err)
+	}
+
+	var text strings.Builder
+	if len(results) == 0 {
+		fmt.Fprintf(&text, "No commands match the query '%s'.\n", query)
+	} else {
+		fmt.Fprintf(&text, "Here are the commands that best match the query '%s', best match first.\n", query)
+		text.WriteString("Run again with the \"tool\", \"command\", and \"parameters\" arguments to execute one of them.\n\n")
+		text.Write(resultsJson)
+		text.WriteString("\n")
+	}
+	if len(unknown) > 0 {
+		fmt.Fprintf(&text, "\nThe commands of these tools have not been learned yet and were not searched: %s\n", strings.Join(unknown, ", "))
+		text.WriteString("Run again with the \"learn\" argument and the \"tool\" name to learn about them.\n")
+	}
+
+	return mcp.NewToolResultText(text.String()), nil
+}
+
+// knownCommands returns the commands of a child tool from the schema cache, or
+// as listed since the server started, without starting the child.
+func (a *AzureTool) knownCommands(toolName string) ([]mcp.Tool, bool) {
+	if commands, ok := a.schemas.Tools(a.toolMetadataMap[toolName]); ok {
+		return commands, true
+	}
+
+	a.listedMu.Lock()
+	defer a.listedMu.Unlock()
+
+	commands, ok := a.listed[toolName]
+	return commands, ok
+}
@@ -0,0 +1,193 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Methods of the resource subscription requests, which mcp-go does not route.
+const (
+	methodResourcesSubscribe   = "resources/subscribe"
+	methodResourcesUnsubscribe = "resources/unsubscribe"
+)
+
+// Subscriptions tracks the resources every session subscribed to, so resources/updated is
+// only sent to the sessions that asked for it.
+//
+// The server answers resources/subscribe and resources/unsubscribe with "method not found",
+// so the transports hand every incoming message to Intercept first. A subscription request is
+// recorded and passed on as a ping, which the server answers with the same empty result.
+type Subscriptions struct {
+	server *server.MCPServer
+
+	mu sync.Mutex
+	// sessions holds the URIs every session subscribed to.
+	sessions map[string]map[string]bool
+}
+
+// NewSubscriptions creates the subscriptions of the sessions of the server.
+func NewSubscriptions(s *server.MCPServer) *Subscriptions {
+	return &Subscriptions{
+		server:   s,
+		sessions: make(map[string]map[string]bool),
+	}
+}
+
+// Intercept records a subscription request of the session and returns it rewritten as a ping.
+// Any other message is returned as it is.
+func (s *Subscriptions) Intercept(sessionID string, message []byte) []byte {
+	if !bytes.Contains(message, []byte(methodResourcesSubscribe)) && !bytes.Contains(message, []byte(methodResourcesUnsubscribe)) {
+		return message
+	}
+
+	var request struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Method  string          `json:"method"`
+		Params  struct {
+			URI string `json:"uri"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(message, &request); err != nil || request.ID == nil || request.Params.URI == "" {
+		return message
+	}
+
+	switch request.Method {
+	case methodResourcesSubscribe:
+		s.subscribe(sessionID, request.Params.URI)
+	case methodResourcesUnsubscribe:
+		s.unsubscribe(sessionID, request.Params.URI)
+	default:
+		return message
+	}
+
+	ping, err := json.Marshal(map[string]any{
+		"jsonrpc": request.JSONRPC,
+		"id":      request.ID,
+		"method":  mcp.MethodPing,
+	})
+	if err != nil {
+		return message
+	}
+	return ping
+}
+
+func (s *Subscriptions) subscribe(sessionID string, uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sessions[sessionID] == nil {
+		s.sessions[sessionID] = make(map[string]bool)
+	}
+	s.sessions[sessionID][uri] = true
+}
+
+func (s *Subscriptions) unsubscribe(sessionID string, uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions[sessionID], uri)
+	if len(s.sessions[sessionID]) == 0 {
+		delete(s.sessions, sessionID)
+	}
+}
+
+// EndSession forgets the subscriptions of a session that has ended.
+func (s *Subscriptions) EndSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, sessionID)
+}
+
+// subscribed reports whether the session subscribed to the resource.
+func (s *Subscriptions) subscribed(sessionID string, uri string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sessions[sessionID][uri]
+}
+
+// notifyUpdated sends resources/updated for the resource to every session subscribed to it.
+func (s *Subscriptions) notifyUpdated(uri string) {
+	s.mu.Lock()
+	var subscribers []string
+	for sessionID, uris := range s.sessions {
+		if uris[uri] {
+			subscribers = append(subscribers, sessionID)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sessionID := range subscribers {
+		s.notifySession(sessionID, uri)
+	}
+}
+
+// notifySession sends resources/updated for the resource to the session, if it subscribed to it.
+func (s *Subscriptions) notifySession(sessionID string, uri string) {
+	if !s.subscribed(sessionID, uri) {
+		return
+	}
+
+	// A session that went away is forgotten when it is unregistered.
+	_ = s.server.SendNotificationToSpecificClient(sessionID, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+}
+
+// Reader intercepts the messages read from the input of the stdio transport, one per line.
+func (s *Subscriptions) Reader(input io.Reader, sessionID string) io.Reader {
+	reader, writer := io.Pipe()
+
+	go func() {
+		lines := bufio.NewReader(input)
+		for {
+			line, err := lines.ReadBytes('\n')
+			if len(line) > 0 {
+				message := s.Intercept(sessionID, bytes.TrimRight(line, "\r\n"))
+				if _, writeErr := writer.Write(append(message, '\n')); writeErr != nil {
+					return
+				}
+			}
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				writer.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	return reader
+}
+
+// Handler intercepts the messages posted to an HTTP transport. sessionID returns the session
+// a request belongs to.
+func (s *Subscriptions) Handler(next http.Handler, sessionID func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := sessionID(r)
+		if r.Method != http.MethodPost || session == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		message := s.Intercept(session, body)
+		r.Body = io.NopCloser(bytes.NewReader(message))
+		r.ContentLength = int64(len(message))
+
+		next.ServeHTTP(w, r)
+	})
+}
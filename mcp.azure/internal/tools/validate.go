@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// parameterProblem describes a single way in which the parameters do not match a command's schema.
+type parameterProblem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (p parameterProblem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// inputSchema returns the command's input schema as a generic JSON schema document.
+func inputSchema(command mcp.Tool) map[string]any {
+	raw := []byte(command.RawInputSchema)
+	if len(raw) == 0 {
+		var err error
+		if raw, err = json.Marshal(command.InputSchema); err != nil {
+			return nil
+		}
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil
+	}
+
+	return schema
+}
+
+// validateParameters checks the parameters against the command's input schema.
+// Only required properties, types, enums and unknown properties of closed objects
+// are checked; anything else is left for the child to reject.
+// With references, the parameters may hold unresolved references to earlier plan steps, as in
+// a dry run of a plan: a value that is a single reference can resolve to any type, so only its
+// presence is checked, and a string containing a reference is not checked against an enum.
+func validateParameters(schema map[string]any, params any, references bool) []parameterProblem {
+	if schema == nil {
+		return nil
+	}
+	if params == nil {
+		params = map[string]any{}
+	}
+
+	return validateValue("parameters", params, schema, references)
+}
+
+func validateValue(path string, value any, schema map[string]any, references bool) []parameterProblem {
+	reference := false
+	if s, ok := value.(string); ok && references && stepReference.MatchString(s) {
+		if stepReference.FindString(s) == s {
+			return nil
+		}
+		reference = true
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool {
+		return hasType(value, t)
+	}) {
+		return []parameterProblem{{
+			Path:    path,
+			Message: fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), jsonType(value)),
+		}}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !reference && !slices.ContainsFunc(enum, func(allowed any) bool {
+		return reflect.DeepEqual(allowed, value)
+	}) {
+		allowed := make([]string, 0, len(enum))
+		for _, v := range enum {
+			encoded, _ := json.Marshal(v)
+			allowed = append(allowed, string(encoded))
+		}
+		return []parameterProblem{{
+			Path:    path,
+			Message: "must be one of " + strings.Join(allowed, ", "),
+		}}
+	}
+
+	var problems []parameterProblem
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+
+		required, _ := schema["required"].([]any)
+		for _, r := range required {
+			name, ok := r.(string)
+			if !ok {
+				continue
+			}
+			if _, has := v[name]; !has {
+				problems = append(problems, parameterProblem{
+					Path:    childPath(path, name),
+					Message: "required parameter is missing",
+				})
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if propertySchema, ok := properties[name].(map[string]any); ok {
+				problems = append(problems, validateValue(childPath(path, name), v[name], propertySchema, references)...)
+				continue
+			}
+			if _, ok := properties[name]; !ok && schema["additionalProperties"] == false {
+				problems = append(problems, parameterProblem{
+					Path:    childPath(path, name),
+					Message: "unknown parameter",
+				})
+			}
+		}
+
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				problems = append(problems, validateValue(fmt.Sprintf("%s[%d]", path, i), item, items, references)...)
+			}
+		}
+	}
+
+	return problems
+}
+
+// schemaTypes returns the allowed JSON types of a schema "type" keyword.
+func schemaTypes(t any) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	default:
+		return nil
+	}
+}
+
+func hasType(value any, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == t
+	}
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// childPath returns the path of a property, top-level parameters are named without a prefix.
+func childPath(path string, name string) string {
+	if path == "parameters" {
+		return name
+	}
+	return path + "." + name
+}
+
+// invalidParametersResult lists every problem with the parameters together with the expected schema.
+func invalidParametersResult(toolName string, commandName string, schema map[string]any, problems []parameterProblem) *mcp.CallToolResult {
+	schemaJson, _ := json.MarshalIndent(schema, "", "  ")
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Invalid parameters for tool: %s, command: %s\n", toolName, commandName)
+	for _, p := range problems {
+		text.WriteString("- " + p.String() + "\n")
+	}
+	fmt.Fprintf(&text, "\nFix the parameters and run again. The expected parameters schema is:\n%s\n", schemaJson)
+
+	return errorResult(toolError{
+		Code:       codeInvalidParameters,
+		Tool:       toolName,
+		Command:    commandName,
+		Message:    fmt.Sprintf("%d invalid parameters", len(problems)),
+		NextAction: "Fix the parameters to match the schema and run again.",
+		Problems:   problems,
+		Schema:     schema,
+	}, text.String())
+}
@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// testSchema is the input schema of a command taking the most common kinds of parameters.
+const testSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string"},
+		"sku": {"type": "string", "enum": ["Standard_LRS", "Premium_LRS"]},
+		"count": {"type": "integer"},
+		"ratio": {"type": "number"},
+		"tags": {"type": "array", "items": {"type": "string"}},
+		"network": {
+			"type": "object",
+			"properties": {"public": {"type": "boolean"}},
+			"additionalProperties": false
+		}
+	},
+	"required": ["name"],
+	"additionalProperties": false
+}`
+
+func TestValidateParameters(t *testing.T) {
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(testSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		params     string
+		references bool
+		want       []parameterProblem
+	}{
+		{
+			name:   "valid",
+			params: `{"name": "st1", "sku": "Standard_LRS", "count": 3, "ratio": 0.5, "tags": ["a"], "network": {"public": false}}`,
+		},
+		{
+			name:   "missing required",
+			params: `{}`,
+			want:   []parameterProblem{{Path: "name", Message: "required parameter is missing"}},
+		},
+		{
+			name:   "unknown parameter",
+			params: `{"name": "st1", "location": "westus"}`,
+			want:   []parameterProblem{{Path: "location", Message: "unknown parameter"}},
+		},
+		{
+			name:   "unknown nested parameter",
+			params: `{"name": "st1", "network": {"private": true}}`,
+			want:   []parameterProblem{{Path: "network.private", Message: "unknown parameter"}},
+		},
+		{
+			name:   "wrong type",
+			params: `{"name": 1}`,
+			want:   []parameterProblem{{Path: "name", Message: "expected string, got number"}},
+		},
+		{
+			name:   "wrong item type",
+			params: `{"name": "st1", "tags": ["a", true]}`,
+			want:   []parameterProblem{{Path: "tags[1]", Message: "expected string, got boolean"}},
+		},
+		{
+			name:   "not in enum",
+			params: `{"name": "st1", "sku": "Basic"}`,
+			want:   []parameterProblem{{Path: "sku", Message: `must be one of "Standard_LRS", "Premium_LRS"`}},
+		},
+		{
+			name:   "integer accepts whole numbers",
+			params: `{"name": "st1", "count": 3.0}`,
+		},
+		{
+			name:   "integer rejects fractions",
+			params: `{"name": "st1", "count": 2.5}`,
+			want:   []parameterProblem{{Path: "count", Message: "expected integer, got number"}},
+		},
+		{
+			name:   "number accepts fractions",
+			params: `{"name": "st1", "ratio": 2.5}`,
+		},
+		{
+			name:   "number rejects strings",
+			params: `{"name": "st1", "ratio": "2.5"}`,
+			want:   []parameterProblem{{Path: "ratio", Message: "expected number, got string"}},
+		},
+		{
+			name:       "whole-value reference skips the type check",
+			params:     `{"name": "st1", "count": "${steps.0.count}", "network": "${steps.0.network}"}`,
+			references: true,
+		},
+		{
+			name:   "whole-value reference is checked outside of plans",
+			params: `{"name": "st1", "count": "${steps.0.count}"}`,
+			want:   []parameterProblem{{Path: "count", Message: "expected integer, got string"}},
+		},
+		{
+			name:       "embedded reference skips the enum check",
+			params:     `{"name": "st1", "sku": "${steps.0.tier}_LRS"}`,
+			references: true,
+		},
+		{
+			name:       "embedded reference keeps the type check",
+			params:     `{"name": "st1", "count": "${steps.0.count}0"}`,
+			references: true,
+			want:       []parameterProblem{{Path: "count", Message: "expected integer, got string"}},
+		},
+		{
+			name:       "reference keeps the required check",
+			params:     `{"sku": "${steps.0.sku}"}`,
+			references: true,
+			want:       []parameterProblem{{Path: "name", Message: "required parameter is missing"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params map[string]any
+			if err := json.Unmarshal([]byte(tt.params), &params); err != nil {
+				t.Fatal(err)
+			}
+
+			got := validateParameters(schema, params, tt.references)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateParameters(%s) = %v, want %v", tt.params, got, tt.want)
+			}
+		})
+	}
+}
@@ -18,16 +18,21 @@ func newServerCommand() *cobra.Command {
 	startCmd := &cobra.Command{
 		Use: "start",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Tool calls cancelled by the client stop the az and azd processes they started.
+			cancellations := newCancellations()
 			s := server.NewMCPServer(
 				"Azure Cosmos Accounts",
 				"1.0.0",
 				server.WithToolCapabilities(true),
+				server.WithHooks(cancellations.hooks()),
+				server.WithToolHandlerMiddleware(cancellations.middleware),
 				server.WithRecovery(),
 				server.WithLogging(),
 				server.WithPromptCapabilities(false),
 				server.WithResourceCapabilities(false, false),
 				server.WithInstructions("Supports tools for interacting with Azure accounts, subscriptions and locations."),
 			)
+			s.AddNotificationHandler(methodNotificationCancelled, cancellations.handleCancelled)
 
 			// Register Cosmos DB tools using the mcp.NewTool and s.AddTool pattern, matching mcp.resource
 			// Each tool is defined with mcp.NewTool (name, description, parameters) and registered with a handler that validates arguments and calls the az CLI
@@ -56,7 +61,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroup, expected string"), nil
 				}
-				cmd := exec.Command("az", "cosmosdb", "create", "--name", name, "--resource-group", rg)
+				cmd := exec.CommandContext(ctx, "az", "cosmosdb", "create", "--name", name, "--resource-group", rg)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -75,7 +80,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroup, expected string"), nil
 				}
-				cmd := exec.Command("az", "cosmosdb", "list", "--resource-group", rg)
+				cmd := exec.CommandContext(ctx, "az", "cosmosdb", "list", "--resource-group", rg)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -103,7 +108,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroup, expected string"), nil
 				}
-				cmd := exec.Command("az", "cosmosdb", "show", "--name", name, "--resource-group", rg)
+				cmd := exec.CommandContext(ctx, "az", "cosmosdb", "show", "--name", name, "--resource-group", rg)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -138,7 +143,7 @@ func newServerCommand() *cobra.Command {
 				if set != "" {
 					args = append(args, "--set", set)
 				}
-				cmd := exec.Command("az", args...)
+				cmd := exec.CommandContext(ctx, "az", args...)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -166,7 +171,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroup, expected string"), nil
 				}
-				cmd := exec.Command("az", "cosmosdb", "delete", "--name", name, "--resource-group", rg)
+				cmd := exec.CommandContext(ctx, "az", "cosmosdb", "delete", "--name", name, "--resource-group", rg)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -194,7 +199,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroup, expected string"), nil
 				}
-				cmd := exec.Command("az", "cosmosdb", "sql", "database", "list", "--account-name", name, "--resource-group", rg)
+				cmd := exec.CommandContext(ctx, "az", "cosmosdb", "sql", "database", "list", "--account-name", name, "--resource-group", rg)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -231,7 +236,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: databaseName, expected string"), nil
 				}
-				cmd := exec.Command("az", "cosmosdb", "sql", "container", "list", "--account-name", name, "--resource-group", rg, "--database-name", db)
+				cmd := exec.CommandContext(ctx, "az", "cosmosdb", "sql", "container", "list", "--account-name", name, "--resource-group", rg, "--database-name", db)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -259,7 +264,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroup, expected string"), nil
 				}
-				cmd := exec.Command("az", "cosmosdb", "mongodb", "database", "list", "--account-name", name, "--resource-group", rg)
+				cmd := exec.CommandContext(ctx, "az", "cosmosdb", "mongodb", "database", "list", "--account-name", name, "--resource-group", rg)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -296,7 +301,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: databaseName, expected string"), nil
 				}
-				cmd := exec.Command("az", "cosmosdb", "mongodb", "collection", "list", "--account-name", name, "--resource-group", rg, "--database-name", db)
+				cmd := exec.CommandContext(ctx, "az", "cosmosdb", "mongodb", "collection", "list", "--account-name", name, "--resource-group", rg, "--database-name", db)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -333,7 +338,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: databaseName, expected string"), nil
 				}
-				cmd := exec.Command("az", "cosmosdb", "sql", "database", "create", "--account-name", name, "--resource-group", rg, "--name", db)
+				cmd := exec.CommandContext(ctx, "az", "cosmosdb", "sql", "database", "create", "--account-name", name, "--resource-group", rg, "--name", db)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -370,7 +375,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: databaseName, expected string"), nil
 				}
-				cmd := exec.Command("az", "cosmosdb", "mongodb", "database", "create", "--account-name", name, "--resource-group", rg, "--name", db)
+				cmd := exec.CommandContext(ctx, "az", "cosmosdb", "mongodb", "database", "create", "--account-name", name, "--resource-group", rg, "--name", db)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -425,7 +430,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: partitionKeyPath, expected string"), nil
 				}
-				cmd := exec.Command("az", "cosmosdb", "sql", "container", "create", "--account-name", name, "--resource-group", rg, "--database-name", db, "--name", container, "--partition-key-path", pk)
+				cmd := exec.CommandContext(ctx, "az", "cosmosdb", "sql", "container", "create", "--account-name", name, "--resource-group", rg, "--database-name", db, "--name", container, "--partition-key-path", pk)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -480,7 +485,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: shard, expected string"), nil
 				}
-				cmd := exec.Command("az", "cosmosdb", "mongodb", "collection", "create", "--account-name", account, "--resource-group", rg, "--database-name", db, "--name", coll, "--shard", shard)
+				cmd := exec.CommandContext(ctx, "az", "cosmosdb", "mongodb", "collection", "create", "--account-name", account, "--resource-group", rg, "--database-name", db, "--name", coll, "--shard", shard)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -28,16 +28,21 @@ func newServerCommand() *cobra.Command {
 	startCmd := &cobra.Command{
 		Use: "start",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Tool calls cancelled by the client stop the az and azd processes they started.
+			cancellations := newCancellations()
 			s := server.NewMCPServer(
 				"KeyVault",
 				"1.0.0",
 				server.WithToolCapabilities(true),
+				server.WithHooks(cancellations.hooks()),
+				server.WithToolHandlerMiddleware(cancellations.middleware),
 				server.WithRecovery(),
 				server.WithLogging(),
 				server.WithPromptCapabilities(false),
 				server.WithResourceCapabilities(false, false),
 				server.WithInstructions("Supports tools for interacting with Azure Key Vaults and secrets."),
 			)
+			s.AddNotificationHandler(methodNotificationCancelled, cancellations.handleCancelled)
 
 			// list-keyvaults
 			listKeyVaultsTool := mcp.NewTool(
@@ -54,7 +59,7 @@ func newServerCommand() *cobra.Command {
 						args = append(args, "--resource-group", rgStr)
 					}
 				}
-				azCmd := exec.Command("az", args...)
+				azCmd := exec.CommandContext(ctx, "az", args...)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -100,7 +105,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: location, expected string"), nil
 				}
-				azCmd := exec.Command("az", "keyvault", "create", "--name", name, "--resource-group", group, "--location", location)
+				azCmd := exec.CommandContext(ctx, "az", "keyvault", "create", "--name", name, "--resource-group", group, "--location", location)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -134,7 +139,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroupName, expected string"), nil
 				}
-				azCmd := exec.Command("az", "keyvault", "delete", "--name", name, "--resource-group", group)
+				azCmd := exec.CommandContext(ctx, "az", "keyvault", "delete", "--name", name, "--resource-group", group)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -156,7 +161,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: vaultName, expected string"), nil
 				}
-				azCmd := exec.Command("az", "keyvault", "secret", "list", "--vault-name", vault)
+				azCmd := exec.CommandContext(ctx, "az", "keyvault", "secret", "list", "--vault-name", vault)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -190,7 +195,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroupName, expected string"), nil
 				}
-				azCmd := exec.Command("az", "keyvault", "show", "--name", name, "--resource-group", group)
+				azCmd := exec.CommandContext(ctx, "az", "keyvault", "show", "--name", name, "--resource-group", group)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -224,7 +229,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: secretName, expected string"), nil
 				}
-				azCmd := exec.Command("az", "keyvault", "secret", "show", "--vault-name", vault, "--name", secret)
+				azCmd := exec.CommandContext(ctx, "az", "keyvault", "secret", "show", "--vault-name", vault, "--name", secret)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -270,7 +275,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: value, expected string"), nil
 				}
-				azCmd := exec.Command("az", "keyvault", "secret", "set", "--vault-name", vault, "--name", secret, "--value", value)
+				azCmd := exec.CommandContext(ctx, "az", "keyvault", "secret", "set", "--vault-name", vault, "--name", secret, "--value", value)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -304,7 +309,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: secretName, expected string"), nil
 				}
-				azCmd := exec.Command("az", "keyvault", "secret", "delete", "--vault-name", vault, "--name", secret)
+				azCmd := exec.CommandContext(ctx, "az", "keyvault", "secret", "delete", "--vault-name", vault, "--name", secret)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// methodNotificationCancelled is sent by the client to cancel a request it no longer waits for.
+const methodNotificationCancelled = "notifications/cancelled"
+
+// cancellations cancels the context of a running tool call when the client sends
+// notifications/cancelled for it, which mcp-go does not do by itself. The az and azd
+// processes are started with the context of the call, so they are killed with it.
+type cancellations struct {
+	mu    sync.Mutex
+	calls map[string]context.CancelFunc
+	// pending holds the ids of the tools/call requests between the BeforeCallTool hook and the
+	// tool handler, keyed by the context of the request, which mcp-go gives both of them.
+	pending map[context.Context]string
+}
+
+func newCancellations() *cancellations {
+	return &cancellations{
+		calls:   make(map[string]context.CancelFunc),
+		pending: make(map[context.Context]string),
+	}
+}
+
+// hooks records the id of every tools/call request, which the tool handler is not given otherwise.
+func (c *cancellations) hooks() *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddBeforeCallTool(func(ctx context.Context, id any, request *mcp.CallToolRequest) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.pending[ctx] = mcp.NewRequestId(id).String()
+	})
+	hooks.AddOnError(func(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
+		if method != mcp.MethodToolsCall {
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		delete(c.pending, ctx)
+	})
+
+	return hooks
+}
+
+// middleware is a server.ToolHandlerMiddleware that makes tool calls cancellable.
+func (c *cancellations) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		c.mu.Lock()
+		id, ok := c.pending[ctx]
+		delete(c.pending, ctx)
+		c.mu.Unlock()
+		if !ok {
+			return next(ctx, request)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		c.mu.Lock()
+		c.calls[id] = cancel
+		c.mu.Unlock()
+
+		defer func() {
+			c.mu.Lock()
+			delete(c.calls, id)
+			c.mu.Unlock()
+		}()
+
+		return next(ctx, request)
+	}
+}
+
+// handleCancelled is the server.NotificationHandlerFunc for notifications/cancelled.
+func (c *cancellations) handleCancelled(ctx context.Context, notification mcp.JSONRPCNotification) {
+	requestID, ok := notification.Params.AdditionalFields["requestId"]
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	cancel, ok := c.calls[mcp.NewRequestId(requestID).String()]
+	c.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
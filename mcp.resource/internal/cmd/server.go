@@ -18,16 +18,21 @@ func newServerCommand() *cobra.Command {
 	startCmd := &cobra.Command{
 		Use: "start",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Tool calls cancelled by the client stop the az and azd processes they started.
+			cancellations := newCancellations()
 			s := server.NewMCPServer(
 				"Azure Resources",
 				"1.0.0",
 				server.WithToolCapabilities(true),
+				server.WithHooks(cancellations.hooks()),
+				server.WithToolHandlerMiddleware(cancellations.middleware),
 				server.WithRecovery(),
 				server.WithLogging(),
 				server.WithPromptCapabilities(false),
 				server.WithResourceCapabilities(false, false),
 				server.WithInstructions("Supports tools for interacting with Azure subscriptions, resource groups and generic resources."),
 			)
+			s.AddNotificationHandler(methodNotificationCancelled, cancellations.handleCancelled)
 
 			// Resource group tools
 			listResourceGroupsTool := mcp.NewTool(
@@ -47,7 +52,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: subscriptionId, expected string"), nil
 				}
-				azCmd := exec.Command("az", "group", "list", "--subscription", subId)
+				azCmd := exec.CommandContext(ctx, "az", "group", "list", "--subscription", subId)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -92,7 +97,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: subscriptionId, expected string"), nil
 				}
-				azCmd := exec.Command("az", "group", "create", "--name", name, "--location", location, "--subscription", subId)
+				azCmd := exec.CommandContext(ctx, "az", "group", "create", "--name", name, "--location", location, "--subscription", subId)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -125,7 +130,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: subscriptionId, expected string"), nil
 				}
-				azCmd := exec.Command("az", "group", "show", "--name", name, "--subscription", subId)
+				azCmd := exec.CommandContext(ctx, "az", "group", "show", "--name", name, "--subscription", subId)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -143,7 +148,7 @@ func newServerCommand() *cobra.Command {
 						args = append(args, "--resource-group", name)
 					}
 				}
-				azCmd := exec.Command("az", args...)
+				azCmd := exec.CommandContext(ctx, "az", args...)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -182,7 +187,7 @@ func newServerCommand() *cobra.Command {
 						args = append(args, "--subscription", subId)
 					}
 				}
-				azCmd := exec.Command("az", args...)
+				azCmd := exec.CommandContext(ctx, "az", args...)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -217,7 +222,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: subscriptionId, expected string"), nil
 				}
-				azCmd := exec.Command("az", "group", "delete", "--name", name, "--subscription", subId, "--yes")
+				azCmd := exec.CommandContext(ctx, "az", "group", "delete", "--name", name, "--subscription", subId, "--yes")
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -252,7 +257,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: subscriptionId, expected string"), nil
 				}
-				azCmd := exec.Command("az", "group", "exists", "--name", name, "--subscription", subId)
+				azCmd := exec.CommandContext(ctx, "az", "group", "exists", "--name", name, "--subscription", subId)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -3,22 +3,29 @@ module mcp.roles
 go 1.24.1
 
 require (
-	github.com/Masterminds/semver/v3 v3.3.1
 	github.com/fatih/color v1.18.0
-	github.com/mark3labs/mcp-go v0.30.1
+	// Older mcp-go servers handle stdio messages one at a time, so notifications/cancelled
+	// could not reach a running tool call to stop its az or azd process.
+	github.com/mark3labs/mcp-go v0.44.0
 	github.com/spf13/cobra v1.9.1
 )
 
 require (
+	github.com/bahlo/generic-list-go v0.2.0 // indirect
+	github.com/buger/jsonparser v1.1.1 // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/invopop/jsonschema v0.13.0 // indirect
+	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/rogpeppe/go-internal v1.12.0 // indirect
 	github.com/spf13/cast v1.7.1 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
+	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
 	golang.org/x/sys v0.32.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
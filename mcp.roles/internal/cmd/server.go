@@ -18,16 +18,21 @@ func newServerCommand() *cobra.Command {
 	startCmd := &cobra.Command{
 		Use: "start",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Tool calls cancelled by the client stop the az and azd processes they started.
+			cancellations := newCancellations()
 			s := server.NewMCPServer(
 				"Azure Role Assignments",
 				"1.0.0",
 				server.WithToolCapabilities(true),
+				server.WithHooks(cancellations.hooks()),
+				server.WithToolHandlerMiddleware(cancellations.middleware),
 				server.WithRecovery(),
 				server.WithLogging(),
 				server.WithPromptCapabilities(false),
 				server.WithResourceCapabilities(false, false),
 				server.WithInstructions("Supports tool automation for Azure role assignments, including listing, creating, and deleting role assignments and definitions."),
 			)
+			s.AddNotificationHandler(methodNotificationCancelled, cancellations.handleCancelled)
 
 			// az role assignment list
 			roleAssignmentListTool := mcp.NewTool(
@@ -35,7 +40,7 @@ func newServerCommand() *cobra.Command {
 				mcp.WithDescription("List role assignments."),
 			)
 			s.AddTool(roleAssignmentListTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-				azCmd := exec.Command("az", "role", "assignment", "list")
+				azCmd := exec.CommandContext(ctx, "az", "role", "assignment", "list")
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -58,7 +63,7 @@ func newServerCommand() *cobra.Command {
 				if scope, ok := request.GetArguments()["scope"].(string); ok && scope != "" {
 					args = append(args, "--scope", scope)
 				}
-				azCmd := exec.Command("az", args...)
+				azCmd := exec.CommandContext(ctx, "az", args...)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -81,7 +86,7 @@ func newServerCommand() *cobra.Command {
 				if scope, ok := request.GetArguments()["scope"].(string); ok && scope != "" {
 					args = append(args, "--scope", scope)
 				}
-				azCmd := exec.Command("az", args...)
+				azCmd := exec.CommandContext(ctx, "az", args...)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -91,7 +96,7 @@ func newServerCommand() *cobra.Command {
 				mcp.WithDescription("List custom and built-in role definitions."),
 			)
 			s.AddTool(roleDefinitionListTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-				azCmd := exec.Command("az", "role", "definition", "list")
+				azCmd := exec.CommandContext(ctx, "az", "role", "definition", "list")
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -103,7 +108,7 @@ func newServerCommand() *cobra.Command {
 			)
 			s.AddTool(roleDefinitionCreateTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 				roleDef, _ := request.GetArguments()["roleDefinition"].(string)
-				azCmd := exec.Command("az", "role", "definition", "create", "--role-definition", roleDef)
+				azCmd := exec.CommandContext(ctx, "az", "role", "definition", "create", "--role-definition", roleDef)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -115,7 +120,7 @@ func newServerCommand() *cobra.Command {
 			)
 			s.AddTool(roleDefinitionUpdateTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 				roleDef, _ := request.GetArguments()["roleDefinition"].(string)
-				azCmd := exec.Command("az", "role", "definition", "update", "--role-definition", roleDef)
+				azCmd := exec.CommandContext(ctx, "az", "role", "definition", "update", "--role-definition", roleDef)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -127,7 +132,7 @@ func newServerCommand() *cobra.Command {
 			)
 			s.AddTool(roleDefinitionDeleteTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 				name, _ := request.GetArguments()["name"].(string)
-				azCmd := exec.Command("az", "role", "definition", "delete", "--name", name)
+				azCmd := exec.CommandContext(ctx, "az", "role", "definition", "delete", "--name", name)
 				return runAzCommandWithResult(azCmd), nil
 			})
 
@@ -18,16 +18,21 @@ func newServerCommand() *cobra.Command {
 	startCmd := &cobra.Command{
 		Use: "start",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Tool calls cancelled by the client stop the az and azd processes they started.
+			cancellations := newCancellations()
 			s := server.NewMCPServer(
 				"Azure Service Bus Namespaces",
 				"1.0.0",
 				server.WithToolCapabilities(true),
+				server.WithHooks(cancellations.hooks()),
+				server.WithToolHandlerMiddleware(cancellations.middleware),
 				server.WithRecovery(),
 				server.WithLogging(),
 				server.WithPromptCapabilities(false),
 				server.WithResourceCapabilities(false, false),
 				server.WithInstructions("Supports tools for interacting with Azure Service Bus namespaces."),
 			)
+			s.AddNotificationHandler(methodNotificationCancelled, cancellations.handleCancelled)
 
 			// Service Bus: Create Namespace
 			createNamespaceTool := mcp.NewTool(
@@ -62,7 +67,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: location, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "namespace", "create", "--name", name, "--resource-group", rg, "--location", loc)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "namespace", "create", "--name", name, "--resource-group", rg, "--location", loc)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -81,7 +86,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroup, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "namespace", "list", "--resource-group", rg)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "namespace", "list", "--resource-group", rg)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -109,7 +114,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroup, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "namespace", "show", "--name", name, "--resource-group", rg)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "namespace", "show", "--name", name, "--resource-group", rg)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -144,7 +149,7 @@ func newServerCommand() *cobra.Command {
 				if set != "" {
 					args = append(args, "--set", set)
 				}
-				cmd := exec.Command("az", args...)
+				cmd := exec.CommandContext(ctx, "az", args...)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -172,7 +177,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroup, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "namespace", "delete", "--name", name, "--resource-group", rg)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "namespace", "delete", "--name", name, "--resource-group", rg)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -209,7 +214,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: queueName, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "queue", "create", "--namespace-name", ns, "--resource-group", rg, "--name", queue)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "queue", "create", "--namespace-name", ns, "--resource-group", rg, "--name", queue)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -237,7 +242,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroup, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "queue", "list", "--namespace-name", ns, "--resource-group", rg)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "queue", "list", "--namespace-name", ns, "--resource-group", rg)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -274,7 +279,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: queueName, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "queue", "show", "--namespace-name", ns, "--resource-group", rg, "--name", queue)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "queue", "show", "--namespace-name", ns, "--resource-group", rg, "--name", queue)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -318,7 +323,7 @@ func newServerCommand() *cobra.Command {
 				if set != "" {
 					args = append(args, "--set", set)
 				}
-				cmd := exec.Command("az", args...)
+				cmd := exec.CommandContext(ctx, "az", args...)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -355,7 +360,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: queueName, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "queue", "delete", "--namespace-name", ns, "--resource-group", rg, "--name", queue)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "queue", "delete", "--namespace-name", ns, "--resource-group", rg, "--name", queue)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -392,7 +397,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: topicName, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "topic", "create", "--namespace-name", ns, "--resource-group", rg, "--name", topic)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "topic", "create", "--namespace-name", ns, "--resource-group", rg, "--name", topic)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -420,7 +425,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: resourceGroup, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "topic", "list", "--namespace-name", ns, "--resource-group", rg)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "topic", "list", "--namespace-name", ns, "--resource-group", rg)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -457,7 +462,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: topicName, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "topic", "show", "--namespace-name", ns, "--resource-group", rg, "--name", topic)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "topic", "show", "--namespace-name", ns, "--resource-group", rg, "--name", topic)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -501,7 +506,7 @@ func newServerCommand() *cobra.Command {
 				if set != "" {
 					args = append(args, "--set", set)
 				}
-				cmd := exec.Command("az", args...)
+				cmd := exec.CommandContext(ctx, "az", args...)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -538,7 +543,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: topicName, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "topic", "delete", "--namespace-name", ns, "--resource-group", rg, "--name", topic)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "topic", "delete", "--namespace-name", ns, "--resource-group", rg, "--name", topic)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -584,7 +589,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: subscriptionName, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "topic", "subscription", "create", "--namespace-name", ns, "--resource-group", rg, "--topic-name", topic, "--name", sub)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "topic", "subscription", "create", "--namespace-name", ns, "--resource-group", rg, "--topic-name", topic, "--name", sub)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -621,7 +626,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: topicName, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "topic", "subscription", "list", "--namespace-name", ns, "--resource-group", rg, "--topic-name", topic)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "topic", "subscription", "list", "--namespace-name", ns, "--resource-group", rg, "--topic-name", topic)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -667,7 +672,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: subscriptionName, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "topic", "subscription", "show", "--namespace-name", ns, "--resource-group", rg, "--topic-name", topic, "--name", sub)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "topic", "subscription", "show", "--namespace-name", ns, "--resource-group", rg, "--topic-name", topic, "--name", sub)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -720,7 +725,7 @@ func newServerCommand() *cobra.Command {
 				if set != "" {
 					args = append(args, "--set", set)
 				}
-				cmd := exec.Command("az", args...)
+				cmd := exec.CommandContext(ctx, "az", args...)
 				return runAzCommandWithResult(cmd), nil
 			})
 
@@ -766,7 +771,7 @@ func newServerCommand() *cobra.Command {
 				if !ok {
 					return mcp.NewToolResultText("Invalid type for argument: subscriptionName, expected string"), nil
 				}
-				cmd := exec.Command("az", "servicebus", "topic", "subscription", "delete", "--namespace-name", ns, "--resource-group", rg, "--topic-name", topic, "--name", sub)
+				cmd := exec.CommandContext(ctx, "az", "servicebus", "topic", "subscription", "delete", "--namespace-name", ns, "--resource-group", rg, "--topic-name", topic, "--name", sub)
 				return runAzCommandWithResult(cmd), nil
 			})
 